@@ -26,9 +26,20 @@ func (controller *HealthCheckController) GetRoutes() []string {
 	return []string{http.HealthCheckRoute}
 }
 
-// Get returns a new instance of the HealthCheckResource
+// AllowedMethods only includes GET.
+func (controller *HealthCheckController) AllowedMethods() []string {
+	return []string{nhttp.MethodGet}
+}
+
+// Get returns a new instance of the HealthCheckResource, while the server is
+// draining the status is reported as unavailable.
 func (controller *HealthCheckController) Get(context *http.Context) {
 	model := HealthCheckResource{Timestamp: time.Now().UTC().Format(time.RFC822),
 		Status: "OK"}
+	if context.Draining() {
+		model.Status = "DRAINING"
+		context.SetResponse(model, nhttp.StatusServiceUnavailable)
+		return
+	}
 	context.SetResponse(model, nhttp.StatusOK)
 }
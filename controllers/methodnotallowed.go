@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"net/http"
-
 	qerror "github.com/Kasita-Inc/quimby/error"
 	qhttp "github.com/Kasita-Inc/quimby/http"
 )
@@ -18,30 +16,31 @@ func (controller MethodNotAllowedController) GetRoutes() []string {
 
 // Get returns a method not allowed status
 func (controller MethodNotAllowedController) Get(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
 
 // Post returns a method not allowed status
 func (controller MethodNotAllowedController) Post(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
 
 // Put returns a method not allowed status
 func (controller MethodNotAllowedController) Put(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
 
 // Patch returns a method not allowed status
 func (controller MethodNotAllowedController) Patch(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
 
 // Delete returns a method not allowed status
 func (controller MethodNotAllowedController) Delete(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
 
-// Options returns a method not allowed status
+// Options returns a method not allowed status, which the server answers with
+// the methods allowed for the route.
 func (controller MethodNotAllowedController) Options(context *qhttp.Context) {
-	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+	context.Fail(qerror.MethodNotAllowed)
 }
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"bytes"
+	nhttp "net/http"
+
+	"github.com/Kasita-Inc/quimby/http"
+)
+
+// MetricsController exposes the Metrics recorded by a RESTServer in the
+// Prometheus text exposition format.
+type MetricsController struct {
+	MethodNotAllowedController
+	NoAuthenticationController
+	Metrics *http.Metrics
+}
+
+// NewMetricsController creates a MetricsController for the passed Metrics.
+func NewMetricsController(metrics *http.Metrics) *MetricsController {
+	return &MetricsController{Metrics: metrics}
+}
+
+// GetRoutes only responds on the 'metrics' resource endpoint.
+func (controller *MetricsController) GetRoutes() []string {
+	return []string{http.MetricsRoute}
+}
+
+// AllowedMethods only includes GET.
+func (controller *MetricsController) AllowedMethods() []string {
+	return []string{nhttp.MethodGet}
+}
+
+// Get writes the current metrics.
+func (controller *MetricsController) Get(context *http.Context) {
+	b := &bytes.Buffer{}
+	controller.Metrics.WriteTo(b)
+	context.Response.Header().Set("Content-Type", http.MetricsContentType)
+	context.SetResponse(b.String(), nhttp.StatusOK)
+}
@@ -0,0 +1,38 @@
+// Command errorcodes writes a Markdown listing of the error codes registered
+// by quimby for API documentation. Applications registering their own codes
+// can write a listing including them with qerror.WriteDefinitions.
+//
+//	go run ./error/cmd/errorcodes -o ERROR_CODES.md
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/Kasita-Inc/gadget/log"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+const header = "# Error Codes\n\nGenerated by `go generate ./error`, do not edit.\n\n"
+
+func main() {
+	output := flag.String("o", "", "file to write the listing to, defaults to stdout")
+	flag.Parse()
+
+	var w io.Writer = os.Stdout
+	if "" != *output {
+		file, err := os.Create(*output)
+		if nil != err {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if _, err := io.WriteString(w, header); nil != err {
+		log.Fatal(err)
+	}
+	if err := qerror.WriteDefinitions(w); nil != err {
+		log.Fatal(err)
+	}
+}
@@ -3,6 +3,22 @@ package error
 const (
 	// CannotBeBlank indicates a field that was submitted blank, but is required
 	CannotBeBlank = "cannot-be-blank"
+	// FieldMismatch indicates a field that does not match the field it must equal, such as a password confirmation
+	FieldMismatch = "field-mismatch"
+	// FileTooLarge indicates an uploaded file that exceeds the maximum file size
+	FileTooLarge = "file-too-large"
+	// InvalidValue indicates a value that cannot be converted to the type of its field
+	InvalidValue = "invalid-value"
+	// InvalidFormat indicates a value that does not have the required format, such as an email address
+	InvalidFormat = "invalid-format"
+	// InvalidLength indicates a value that does not have the required length
+	InvalidLength = "invalid-length"
+	// InvalidOption indicates a value that is not one of the allowed options
+	InvalidOption = "invalid-option"
+	// TooLarge indicates a value or length above the maximum allowed
+	TooLarge = "too-large"
+	// TooSmall indicates a value or length below the minimum allowed
+	TooSmall = "too-small"
 	// ValidationError indicates that a validation rule such as min / max value was violated
 	ValidationError = "validation-error"
 )
@@ -4,9 +4,10 @@ import "fmt"
 
 // RestError represents the standard error returned by the API Gateway
 type RestError struct {
-	Code    string        `json:"code"`
-	Message string        `json:"message"`
-	Details []interface{} `json:"details"`
+	Code      string        `json:"code" xml:"code"`
+	Message   string        `json:"message" xml:"message"`
+	Details   []interface{} `json:"details" xml:"details>detail"`
+	RequestID string        `json:"request_id,omitempty" xml:"request_id,omitempty"`
 }
 
 // NewRestError instantiates a RestError
@@ -30,9 +31,9 @@ func (restError *RestError) AddDetail(errorDetail interface{}) {
 
 // FieldError represents a validation error related to a specific input field
 type FieldError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Field   string `json:"field"`
+	Code    string `json:"code" xml:"code"`
+	Message string `json:"message" xml:"message"`
+	Field   string `json:"field" xml:"field"`
 }
 
 // NewFieldError instantiates a FieldError
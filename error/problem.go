@@ -0,0 +1,212 @@
+package error
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ProblemMediaType is the media type of RFC 7807 problem details
+const ProblemMediaType = "application/problem+json"
+
+// ProblemTypeBase is prefixed to the code of a RestError to form the type
+// URI of its problem details
+var ProblemTypeBase = "urn:quimby:error:"
+
+const (
+	problemType      = "type"
+	problemTitle     = "title"
+	problemStatus    = "status"
+	problemDetail    = "detail"
+	problemInstance  = "instance"
+	problemErrors    = "errors"
+	problemCode      = "code"
+	problemRequestID = "request_id"
+	problemDetails   = "details"
+	blankProblemType = "about:blank"
+)
+
+// Problem represents an RFC 7807 problem details object
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Errors are the FieldErrors of the RestError
+	Errors []FieldError
+	// Extensions are the additional members such as the error code
+	Extensions map[string]interface{}
+}
+
+// NewProblem maps a RestError returned with the passed status for the
+// request to the passed instance URI to problem details. The error code,
+// request ID and details other than FieldErrors are extension members.
+func NewProblem(restError *RestError, status int, instance string) *Problem {
+	problem := &Problem{
+		Type:       blankProblemType,
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     restError.Message,
+		Instance:   instance,
+		Extensions: map[string]interface{}{},
+	}
+	if "" != restError.Code {
+		problem.Type = ProblemTypeBase + restError.Code
+		problem.Extensions[problemCode] = restError.Code
+	}
+	if "" != restError.RequestID {
+		problem.Extensions[problemRequestID] = restError.RequestID
+	}
+	details := []interface{}{}
+	for _, detail := range restError.Details {
+		switch fieldError := detail.(type) {
+		case FieldError:
+			problem.Errors = append(problem.Errors, fieldError)
+		case *FieldError:
+			problem.Errors = append(problem.Errors, *fieldError)
+		default:
+			details = append(details, detail)
+		}
+	}
+	if len(details) > 0 {
+		problem.Extensions[problemDetails] = details
+	}
+	return problem
+}
+
+// RestError maps the problem details back to a RestError
+func (problem *Problem) RestError() *RestError {
+	restError := &RestError{Message: problem.Detail}
+	if code, ok := problem.Extensions[problemCode].(string); ok {
+		restError.Code = code
+	} else if strings.HasPrefix(problem.Type, ProblemTypeBase) {
+		restError.Code = strings.TrimPrefix(problem.Type, ProblemTypeBase)
+	}
+	if requestID, ok := problem.Extensions[problemRequestID].(string); ok {
+		restError.RequestID = requestID
+	}
+	for _, fieldError := range problem.Errors {
+		restError.AddDetail(fieldError)
+	}
+	if details, ok := problem.Extensions[problemDetails].([]interface{}); ok {
+		restError.Details = append(restError.Details, details...)
+	}
+	return restError
+}
+
+// MarshalJSON writes the problem details as a JSON object with the
+// extension members alongside the standard members
+func (problem *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(problem.Extensions)+6)
+	for name, value := range problem.Extensions {
+		members[name] = value
+	}
+	members[problemType] = problem.Type
+	members[problemTitle] = problem.Title
+	if 0 != problem.Status {
+		members[problemStatus] = problem.Status
+	}
+	if "" != problem.Detail {
+		members[problemDetail] = problem.Detail
+	}
+	if "" != problem.Instance {
+		members[problemInstance] = problem.Instance
+	}
+	if len(problem.Errors) > 0 {
+		members[problemErrors] = problem.Errors
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON reads the standard members of the problem details, keeping
+// any other members as extensions
+func (problem *Problem) UnmarshalJSON(data []byte) error {
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	*problem = Problem{Type: blankProblemType, Extensions: map[string]interface{}{}}
+	for name, raw := range members {
+		var target interface{}
+		switch name {
+		case problemType:
+			target = &problem.Type
+		case problemTitle:
+			target = &problem.Title
+		case problemStatus:
+			target = &problem.Status
+		case problemDetail:
+			target = &problem.Detail
+		case problemInstance:
+			target = &problem.Instance
+		case problemErrors:
+			target = &problem.Errors
+		default:
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return err
+			}
+			problem.Extensions[name] = value
+			continue
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeRestError parses an error response body with the passed Content-Type
+// into a RestError, accepting both the RestError format and problem details.
+// Details that are field errors are returned as FieldErrors.
+func DecodeRestError(contentType string, body []byte) (*RestError, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if ProblemMediaType != mediaType && "" != mediaType {
+		return decodeRestError(body)
+	}
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	_, hasTitle := members[problemTitle]
+	_, hasType := members[problemType]
+	if "" == mediaType && !hasTitle && !hasType {
+		return decodeRestError(body)
+	}
+	problem := &Problem{}
+	if err := json.Unmarshal(body, problem); err != nil {
+		return nil, err
+	}
+	restError := problem.RestError()
+	restError.Details = fieldErrorDetails(restError.Details)
+	return restError, nil
+}
+
+func decodeRestError(body []byte) (*RestError, error) {
+	restError := &RestError{}
+	if err := json.Unmarshal(body, restError); err != nil {
+		return nil, err
+	}
+	restError.Details = fieldErrorDetails(restError.Details)
+	return restError, nil
+}
+
+// fieldErrorDetails converts the decoded details that are field errors to
+// FieldErrors
+func fieldErrorDetails(details []interface{}) []interface{} {
+	for i, detail := range details {
+		members, ok := detail.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field, hasField := members["field"].(string)
+		code, _ := members["code"].(string)
+		message, _ := members["message"].(string)
+		if hasField {
+			details[i] = FieldError{Code: code, Message: message, Field: field}
+		}
+	}
+	return details
+}
@@ -0,0 +1,69 @@
+package error
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	assert := assert.New(t)
+	restError := NewRestError(ValidationError, "Invalid widget.", []interface{}{
+		FieldError{Code: CannotBeBlank, Message: "'name' cannot be blank.", Field: "name"},
+		NewFieldError(TooLarge, "'size' must be at most 10.", "size"),
+		"note",
+	})
+	restError.RequestID = "req-1"
+	problem := NewProblem(restError, http.StatusBadRequest, "/widgets")
+
+	b, err := json.Marshal(problem)
+	assert.NoError(err)
+	members := map[string]interface{}{}
+	assert.NoError(json.Unmarshal(b, &members))
+	assert.Equal(ProblemTypeBase+ValidationError, members["type"])
+	assert.Equal("Bad Request", members["title"])
+	assert.Equal(float64(http.StatusBadRequest), members["status"])
+	assert.Equal("Invalid widget.", members["detail"])
+	assert.Equal("/widgets", members["instance"])
+	assert.Equal(ValidationError, members["code"])
+	assert.Equal("req-1", members["request_id"])
+	assert.Equal([]interface{}{"note"}, members["details"])
+	assert.Len(members["errors"], 2)
+
+	decoded := &Problem{}
+	assert.NoError(json.Unmarshal(b, decoded))
+	assert.Equal(problem.Errors, decoded.Errors)
+	restError.Details[1] = *restError.Details[1].(*FieldError)
+	assert.Equal(restError, decoded.RestError())
+}
+
+func TestDecodeRestError(t *testing.T) {
+	assert := assert.New(t)
+	restError := NewRestError(NotFound, "No widget found.", []interface{}{
+		FieldError{Code: InvalidValue, Message: "Invalid 'id'.", Field: "id"},
+	})
+	restError.RequestID = "req-2"
+
+	b, _ := json.Marshal(restError)
+	decoded, err := DecodeRestError("application/json; charset=utf-8", b)
+	assert.NoError(err)
+	assert.Equal(restError, decoded)
+
+	b, _ = json.Marshal(NewProblem(restError, http.StatusNotFound, "/widgets/1"))
+	for _, contentType := range []string{ProblemMediaType, ""} {
+		decoded, err = DecodeRestError(contentType, b)
+		assert.NoError(err)
+		assert.Equal(restError, decoded)
+	}
+
+	// problems from other services without a code extension
+	decoded, err = DecodeRestError(ProblemMediaType,
+		[]byte(`{"type":"https://example.com/out-of-credit","title":"Out of credit","detail":"Balance is 30."}`))
+	assert.NoError(err)
+	assert.Equal(&RestError{Message: "Balance is 30."}, decoded)
+
+	_, err = DecodeRestError(ProblemMediaType, []byte(`{"status":"bad"}`))
+	assert.Error(err)
+}
@@ -0,0 +1,187 @@
+package error
+
+//go:generate go run ./cmd/errorcodes -o ../ERROR_CODES.md
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Definition declares the default HTTP status, message template and
+// documentation of an error code
+type Definition struct {
+	Code string `json:"code"`
+	// Status is the HTTP status returned with the code by Context.Fail
+	Status int `json:"status"`
+	// Message is the default message template, formatted with fmt
+	Message string `json:"message"`
+	// Description documents when the code is returned
+	Description string `json:"description"`
+	// Field codes are returned as the code of a FieldError
+	Field bool `json:"field"`
+}
+
+// Catalog provides translated message templates for error codes
+type Catalog interface {
+	// Message returns the template for the code in the language, such as
+	// 'fr' or 'pt-br'
+	Message(language, code string) (string, bool)
+}
+
+// MapCatalog is a Catalog of message templates keyed by lower case language
+// and then code
+type MapCatalog map[string]map[string]string
+
+// Message returns the template for the code in the language
+func (catalog MapCatalog) Message(language, code string) (string, bool) {
+	message, ok := catalog[strings.ToLower(language)][code]
+	return message, ok
+}
+
+var (
+	definitions   = map[string]Definition{}
+	catalogs      = []Catalog{}
+	registryMutex sync.RWMutex
+)
+
+func init() {
+	for _, definition := range []Definition{
+		{MethodNotAllowed, http.StatusMethodNotAllowed, "The method is not allowed for the resource.",
+			"The request method is not implemented for the route.", false},
+		{MalformedURL, http.StatusBadRequest, "Malformed URL Parameters '%s'.",
+			"The query string of the request could not be parsed.", false},
+		{InvalidRoute, http.StatusInternalServerError, "The request path could not be applied to the route.",
+			"The request path matched a route but its parameters could not be read.", false},
+		{AuthenticationFailed, http.StatusUnauthorized, "Invalid Credentials",
+			"The request was not authenticated by the controller.", false},
+		{NotAuthorized, http.StatusForbidden, "Not authorized to perform this action.",
+			"The authenticated user is not permitted to perform the action.", false},
+		{SystemError, http.StatusInternalServerError, "",
+			"An unexpected error occurred handling the request.", false},
+		{RequestTimeout, http.StatusServiceUnavailable, "The request was not completed in time.",
+			"The request was not completed before the request timeout of the server or route.", false},
+		{NotAcceptable, http.StatusNotAcceptable, "The response cannot be returned in an accepted media type.",
+			"None of the media types in the Accept header have a registered encoder.", false},
+		{UnsupportedMediaType, http.StatusUnsupportedMediaType, "The Content-Type '%s' is not supported.",
+			"The request body has no Content-Type, or one without a registered decoder.", false},
+		{RequestTooLarge, http.StatusRequestEntityTooLarge, "Request body exceeds the maximum size of %d bytes.",
+			"The request body is larger than the maximum body size of the server.", false},
+		{NotFound, http.StatusNotFound, "The requested resource was not found.",
+			"No route matches the request path, or the resource does not exist.", false},
+		{ValidationError, http.StatusBadRequest, "The request is not valid.",
+			"The request failed validation, with a FieldError detail per violation. Bodies that cannot be decoded are returned with a 406 status and the decoding error as the message.", false},
+		{CannotBeBlank, http.StatusBadRequest, "'%s' cannot be blank.",
+			"A required field is missing or blank.", true},
+		{FieldMismatch, http.StatusBadRequest, "'%s' must match '%s'.",
+			"A field does not equal the field it must match, such as a password confirmation.", true},
+		{FileTooLarge, http.StatusBadRequest, "File '%s' exceeds the maximum size of %d bytes.",
+			"An uploaded file is larger than the maximum file size.", true},
+		{InvalidValue, http.StatusBadRequest, "Invalid value '%s' for '%s'.",
+			"A value cannot be converted to the type of its field.", true},
+		{InvalidFormat, http.StatusBadRequest, "'%s' does not have the required format.",
+			"A value does not have the required format, such as an email address or UUID.", true},
+		{InvalidLength, http.StatusBadRequest, "'%s' must have a length of %s.",
+			"A value does not have the required length.", true},
+		{InvalidOption, http.StatusBadRequest, "'%s' must be one of '%s'.",
+			"A value is not one of the allowed options.", true},
+		{TooLarge, http.StatusBadRequest, "'%s' must be at most %s.",
+			"A value or length is above the maximum allowed.", true},
+		{TooSmall, http.StatusBadRequest, "'%s' must be at least %s.",
+			"A value or length is below the minimum allowed.", true},
+	} {
+		definitions[definition.Code] = definition
+	}
+}
+
+// Register adds the definition of an error code to the registry, replacing
+// any existing definition of the code
+func Register(definition Definition) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	definitions[definition.Code] = definition
+}
+
+// Lookup returns the registered definition of the code
+func Lookup(code string) (Definition, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	definition, ok := definitions[code]
+	return definition, ok
+}
+
+// Definitions returns the registered definitions sorted by code
+func Definitions() []Definition {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	sorted := make([]Definition, 0, len(definitions))
+	for _, definition := range definitions {
+		sorted = append(sorted, definition)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Code < sorted[j].Code })
+	return sorted
+}
+
+// RegisterCatalog adds a catalog of translated message templates, catalogs
+// registered first take precedence
+func RegisterCatalog(catalog Catalog) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	catalogs = append(catalogs, catalog)
+}
+
+// Localize returns the message template for the code in the first of the
+// passed languages with a translation, matching 'fr' for 'fr-ca', otherwise
+// the default template of the code
+func Localize(code string, languages ...string) string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	for _, language := range languages {
+		language = strings.ToLower(language)
+		candidates := []string{language}
+		if i := strings.Index(language, "-"); i > 0 {
+			candidates = append(candidates, language[:i])
+		}
+		for _, candidate := range candidates {
+			for _, catalog := range catalogs {
+				if message, ok := catalog.Message(candidate, code); ok {
+					return message
+				}
+			}
+		}
+	}
+	return definitions[code].Message
+}
+
+// Format formats the message template with the passed args, templates are
+// returned as is without args
+func Format(template string, args ...interface{}) string {
+	if 0 == len(args) {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// WriteDefinitions writes a Markdown table of the registered error codes for
+// API documentation
+func WriteDefinitions(w io.Writer) error {
+	rows := []string{
+		"| Code | Status | Field | Message | Description |",
+		"| ---- | ------ | ----- | ------- | ----------- |",
+	}
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	for _, definition := range Definitions() {
+		field := ""
+		if definition.Field {
+			field = "yes"
+		}
+		rows = append(rows, fmt.Sprintf("| `%s` | %d %s | %s | %s | %s |", definition.Code, definition.Status,
+			http.StatusText(definition.Status), field, escape.Replace(definition.Message),
+			escape.Replace(definition.Description)))
+	}
+	_, err := io.WriteString(w, strings.Join(rows, "\n")+"\n")
+	return err
+}
@@ -0,0 +1,55 @@
+package error
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+	definition, ok := Lookup(NotFound)
+	assert.True(ok)
+	assert.Equal(http.StatusNotFound, definition.Status)
+	_, ok = Lookup("unregistered")
+	assert.False(ok)
+
+	Register(Definition{Code: "widget-locked", Status: http.StatusConflict, Message: "Widget '%s' is locked."})
+	definition, ok = Lookup("widget-locked")
+	assert.True(ok)
+	assert.Equal("Widget 'w1' is locked.", Format(definition.Message, "w1"))
+	assert.Equal("Widget '%s' is locked.", Format(definition.Message))
+
+	definitions := Definitions()
+	for i := 1; i < len(definitions); i++ {
+		assert.True(definitions[i-1].Code < definitions[i].Code)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	assert := assert.New(t)
+	RegisterCatalog(MapCatalog{
+		"fr":    {NotFound: "La ressource demandée est introuvable."},
+		"pt-br": {NotFound: "O recurso solicitado não foi encontrado."},
+	})
+	defaultMessage, _ := Lookup(NotFound)
+
+	assert.Equal(defaultMessage.Message, Localize(NotFound))
+	assert.Equal(defaultMessage.Message, Localize(NotFound, "de"))
+	assert.Equal("La ressource demandée est introuvable.", Localize(NotFound, "de", "fr-CA"))
+	assert.Equal("O recurso solicitado não foi encontrado.", Localize(NotFound, "pt-BR", "fr"))
+	assert.Equal("", Localize(SystemError, "fr"))
+}
+
+func TestWriteDefinitions(t *testing.T) {
+	assert := assert.New(t)
+	b := &bytes.Buffer{}
+	assert.NoError(WriteDefinitions(b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(len(Definitions())+2, len(lines))
+	assert.Contains(b.String(), "| `not-found` | 404 Not Found |  | The requested resource was not found. |")
+	assert.Contains(b.String(), "| `cannot-be-blank` | 400 Bad Request | yes |")
+}
@@ -5,7 +5,7 @@ const (
 	MethodNotAllowed = "method-not-allowed"
 	// MalformedURL indicates that the URL was not parsable as input
 	MalformedURL = "malformed-url"
-	// InvalidRoute indicates that the route does not exist
+	// InvalidRoute indicates that the request path could not be applied to the route template
 	InvalidRoute = "invalid-route"
 	// AuthenticationFailed indicates that authentication did not complete successfully
 	AuthenticationFailed = "authentication-failed"
@@ -13,6 +13,14 @@ const (
 	NotAuthorized = "not-authorized"
 	// SystemError indicates that a systemic issue has occurred with the request
 	SystemError = "system-error"
+	// RequestTimeout indicates that the request was not completed before its deadline
+	RequestTimeout = "request-timeout"
+	// NotAcceptable indicates that the response cannot be returned in any media type accepted by the client
+	NotAcceptable = "not-acceptable"
+	// UnsupportedMediaType indicates that the request body is not in a media type that can be decoded
+	UnsupportedMediaType = "unsupported-media-type"
+	// RequestTooLarge indicates that the request body exceeds the maximum size accepted
+	RequestTooLarge = "request-too-large"
 	// NotFound indicates that the requested resource was not found
 	NotFound = "not-found"
 )
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"sync"
 
 	"github.com/Kasita-Inc/gadget/generator"
-	"github.com/Kasita-Inc/gadget/stringutil"
 	"github.com/Kasita-Inc/quimby/controllers"
 	qerror "github.com/Kasita-Inc/quimby/error"
 	qhttp "github.com/Kasita-Inc/quimby/http"
@@ -84,52 +82,13 @@ func (s *WidgetStorage) Update(widget *Widget) error {
 
 // WidgetRequest is the allowed input for a Widget (POST, PUT)
 type WidgetRequest struct {
-	SerialNumber string `json:"serial_number"`
-	Description  string `json:"description"`
-	issues       []string
-}
-
-// Valid indicates if the request is complete
-func (req *WidgetRequest) Valid() bool {
-	req.issues = []string{}
-	if stringutil.IsWhiteSpace(req.SerialNumber) {
-		req.issues = append(req.issues, "SerialNumber cannot be blank")
-	}
-	if stringutil.IsWhiteSpace(req.Description) {
-		req.issues = append(req.issues, "Description cannot be blank")
-	}
-	return 0 == len(req.issues)
-}
-
-// Error returns the error string if the request has issues
-func (req *WidgetRequest) Error() string {
-	if 0 == len(req.issues) {
-		return ""
-	}
-	return strings.Join(req.issues, "\n")
+	SerialNumber string `json:"serial_number" validate:"required,max=64"`
+	Description  string `json:"description" validate:"required"`
 }
 
 // WidgetPatch is the allowed input for a Widget (PATCH)
 type WidgetPatch struct {
-	Description string `json:"description"`
-	issues      []string
-}
-
-// Valid indicates if the request is complete
-func (req *WidgetPatch) Valid() bool {
-	req.issues = []string{}
-	if stringutil.IsWhiteSpace(req.Description) {
-		req.issues = append(req.issues, "Description cannot be blank")
-	}
-	return 0 == len(req.issues)
-}
-
-// Error returns the error string if the request has issues
-func (req *WidgetPatch) Error() string {
-	if 0 == len(req.issues) {
-		return ""
-	}
-	return strings.Join(req.issues, "\n")
+	Description string `json:"description" validate:"required"`
 }
 
 // Widget is an example object representing a non-descript widget
@@ -164,6 +123,11 @@ func (controller *WidgetsController) GetRoutes() []string {
 	}
 }
 
+// AllowedMethods for listing and creating Widgets
+func (controller *WidgetsController) AllowedMethods() []string {
+	return []string{http.MethodGet, http.MethodPost}
+}
+
 // Authenticate always returns true
 func (controller WidgetsController) Authenticate(context *qhttp.Context) bool {
 	return widgetAuthentication(context)
@@ -184,8 +148,8 @@ func (controller *WidgetsController) Get(context *qhttp.Context) {
 // Post to create a widget
 func (controller *WidgetsController) Post(context *qhttp.Context) {
 	req := &WidgetRequest{}
+	// ReadObject sets a FieldError on the context for each validation failure
 	if err := context.ReadObject(req); nil != err {
-		context.SetError(&qerror.RestError{Code: qerror.ValidationError, Message: err.Error()}, http.StatusNotAcceptable)
 		return
 	}
 	widget := controller.storage.Create(req)
@@ -205,6 +169,11 @@ func NewWidgetController(storage *WidgetStorage) *WidgetController {
 	}
 }
 
+// AllowedMethods for operating on a single Widget
+func (controller *WidgetController) AllowedMethods() []string {
+	return []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete}
+}
+
 // Authenticate always returns true
 func (controller WidgetController) Authenticate(context *qhttp.Context) bool {
 	return widgetAuthentication(context)
@@ -239,11 +208,6 @@ func (controller *WidgetController) Delete(context *qhttp.Context) {
 func (controller *WidgetController) Put(context *qhttp.Context) {
 	req := &WidgetRequest{}
 	if err := context.ReadObject(req); nil != err {
-		context.SetError(&qerror.RestError{Code: qerror.ValidationError, Message: err.Error()}, http.StatusNotAcceptable)
-		return
-	}
-	if !req.Valid() {
-		context.SetError(&qerror.RestError{Code: qerror.ValidationError, Message: req.Error()}, http.StatusNotAcceptable)
 		return
 	}
 	widget := &Widget{
@@ -272,11 +236,6 @@ func (controller *WidgetController) Patch(context *qhttp.Context) {
 
 	req := &WidgetPatch{}
 	if err := context.ReadObject(req); nil != err {
-		context.SetError(&qerror.RestError{Code: qerror.ValidationError, Message: err.Error()}, http.StatusNotAcceptable)
-		return
-	}
-	if !req.Valid() {
-		context.SetError(&qerror.RestError{Code: qerror.ValidationError, Message: req.Error()}, http.StatusNotAcceptable)
 		return
 	}
 	widget.Description = req.Description
@@ -302,6 +261,11 @@ func (controller *APIController) GetRoutes() []string {
 	}
 }
 
+// AllowedMethods only includes GET.
+func (controller *APIController) AllowedMethods() []string {
+	return []string{http.MethodGet}
+}
+
 // Get the instructions for the example API
 func (controller *APIController) Get(context *qhttp.Context) {
 	context.Response.Header().Add("Content-Type", "text/html")
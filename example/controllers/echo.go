@@ -25,6 +25,11 @@ func (controller *EchoController) GetRoutes() []string {
 	}
 }
 
+// AllowedMethods returns the methods echo'd by the controller.
+func (controller *EchoController) AllowedMethods() []string {
+	return []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+}
+
 // Get writes the information from the request to the body of the response.
 func (controller *EchoController) Get(context *qhttp.Context) {
 	r := context.Request
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	nhttp "net/http"
 
 	"github.com/Kasita-Inc/quimby/controllers"
 	"github.com/Kasita-Inc/quimby/http"
@@ -20,6 +21,11 @@ func (controller *ResourceController) GetRoutes() []string {
 		"resource/{{id}}/{{subresource}}"}
 }
 
+// AllowedMethods only includes GET.
+func (controller *ResourceController) AllowedMethods() []string {
+	return []string{nhttp.MethodGet}
+}
+
 // Get returns the ID and subresource (if provided)
 func (controller *ResourceController) Get(context *http.Context) {
 	id, _ := context.URIParameters["id"]
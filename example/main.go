@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/Kasita-Inc/gadget/log"
 	qcontrollers "github.com/Kasita-Inc/quimby/controllers"
 	"github.com/Kasita-Inc/quimby/example/controllers"
 	"github.com/Kasita-Inc/quimby/http"
@@ -9,7 +10,9 @@ import (
 func main() {
 	rootController := &qcontrollers.HealthCheckController{}
 	server := http.CreateRESTServer(":8080", rootController)
+	server.Metrics = http.NewMetrics()
 	server.Router.AddController(&qcontrollers.HealthCheckController{})
+	server.Router.AddController(qcontrollers.NewMetricsController(server.Metrics))
 	server.Router.AddController(&controllers.ResourceController{})
 	server.Router.AddController(&controllers.EchoController{})
 
@@ -19,5 +22,7 @@ func main() {
 	server.Router.AddController(controllers.NewWidgetController(storage))
 	server.Router.AddController(controllers.NewWidgetsController(storage))
 
-	server.ListenAndServe()
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,311 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kasita-Inc/gadget/log"
+)
+
+// Redacted replaces values removed from access log entries by a
+// RedactionPolicy.
+const Redacted = "[REDACTED]"
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogEntry describes a completed request.
+type AccessLogEntry struct {
+	Time       time.Time
+	RequestID  string
+	RemoteAddr string
+	Method     string
+	// URI is the request URI with the redacted query string.
+	URI   string
+	Proto string
+	// Header is the redacted request header, limited to the logged headers of
+	// the RedactionPolicy.
+	Header    http.Header
+	Status    int
+	Size      int
+	Latency   time.Duration
+	UserAgent string
+	Referer   string
+	// ErrorCode is the code of the error returned, if any.
+	ErrorCode string
+	// Body is the redacted request body, if it was read by the controller.
+	Body string
+}
+
+// AccessLogger records the requests handled by a RESTServer.
+type AccessLogger interface {
+	Log(entry *AccessLogEntry)
+}
+
+// writeLine writes the passed line to the writer, or to the access log if the
+// writer is nil.
+func writeLine(mutex *sync.Mutex, writer io.Writer, line string) {
+	if nil == writer {
+		log.Accessf("%s", line)
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	io.WriteString(writer, line+"\n")
+}
+
+// JSONAccessLogger writes an entry per request as a JSON object on a single
+// line. Entries are written to the access log if no Writer is set.
+type JSONAccessLogger struct {
+	Writer io.Writer
+	mutex  sync.Mutex
+}
+
+type jsonAccessLogEntry struct {
+	Time       string      `json:"time"`
+	RequestID  string      `json:"request_id,omitempty"`
+	RemoteAddr string      `json:"remote_addr"`
+	Method     string      `json:"method"`
+	URI        string      `json:"uri"`
+	Proto      string      `json:"proto"`
+	Header     http.Header `json:"header,omitempty"`
+	Status     int         `json:"status"`
+	Size       int         `json:"size"`
+	LatencyMS  float64     `json:"latency_ms"`
+	UserAgent  string      `json:"user_agent,omitempty"`
+	Referer    string      `json:"referer,omitempty"`
+	ErrorCode  string      `json:"error,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Log writes the entry as JSON.
+func (logger *JSONAccessLogger) Log(entry *AccessLogEntry) {
+	b, err := json.Marshal(&jsonAccessLogEntry{
+		Time:       entry.Time.UTC().Format(time.RFC3339Nano),
+		RequestID:  entry.RequestID,
+		RemoteAddr: entry.RemoteAddr,
+		Method:     entry.Method,
+		URI:        entry.URI,
+		Proto:      entry.Proto,
+		Header:     entry.Header,
+		Status:     entry.Status,
+		Size:       entry.Size,
+		LatencyMS:  float64(entry.Latency) / float64(time.Millisecond),
+		UserAgent:  entry.UserAgent,
+		Referer:    entry.Referer,
+		ErrorCode:  entry.ErrorCode,
+		Body:       entry.Body,
+	})
+	if err != nil {
+		log.Errorf("failed to marshal access log entry: %s", err)
+		return
+	}
+	writeLine(&logger.mutex, logger.Writer, string(b))
+}
+
+// CLFAccessLogger writes an entry per request in the Common Log Format, or
+// the Combined Log Format including the referer and user agent. Entries are
+// written to the access log if no Writer is set.
+type CLFAccessLogger struct {
+	Writer   io.Writer
+	Combined bool
+	mutex    sync.Mutex
+}
+
+// Log writes the entry in the Common or Combined Log Format.
+func (logger *CLFAccessLogger) Log(entry *AccessLogEntry) {
+	host := entry.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	size := "-"
+	if entry.Size > 0 {
+		size = fmt.Sprintf("%d", entry.Size)
+	}
+	line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s", clfField(host), entry.Time.Format(clfTimeFormat),
+		entry.Method, entry.URI, entry.Proto, entry.Status, size)
+	if logger.Combined {
+		line += fmt.Sprintf(" %q %q", clfField(entry.Referer), clfField(entry.UserAgent))
+	}
+	writeLine(&logger.mutex, logger.Writer, line)
+}
+
+func clfField(value string) string {
+	if "" == value {
+		return "-"
+	}
+	return value
+}
+
+// RedactionPolicy lists the request headers, query parameters, and body
+// fields whose values are replaced with Redacted in access log entries.
+// Names are matched case-insensitively.
+type RedactionPolicy struct {
+	// LoggedHeaders are the request headers included in access log entries,
+	// other headers are never logged as they may carry credentials.
+	LoggedHeaders   []string
+	Headers         []string
+	QueryParameters []string
+	// BodyFields are redacted at any depth of JSON bodies and from form
+	// bodies. Bodies of other content types are not logged.
+	BodyFields []string
+}
+
+// DefaultRedactionPolicy is used when no RedactionPolicy is set on the
+// RESTServer.
+var DefaultRedactionPolicy = &RedactionPolicy{
+	LoggedHeaders:   []string{"Accept", "Accept-Language", "Content-Length", "Content-Type"},
+	Headers:         []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	QueryParameters: []string{"password", "token", "access_token", "api_key"},
+	BodyFields:      []string{"password", "token", "secret", "access_token", "refresh_token"},
+}
+
+// RedactHeader returns a copy of the logged headers of the passed header with
+// the values redacted.
+func (policy *RedactionPolicy) RedactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(policy.LoggedHeaders))
+	for name, values := range header {
+		if !containsFold(policy.LoggedHeaders, name) {
+			continue
+		}
+		if containsFold(policy.Headers, name) {
+			values = []string{Redacted}
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// RedactURI returns the passed request URI with the query parameter values
+// redacted.
+func (policy *RedactionPolicy) RedactURI(uri string) string {
+	i := strings.Index(uri, "?")
+	if i < 0 {
+		return uri
+	}
+	query, err := url.ParseQuery(uri[i+1:])
+	if err != nil {
+		return uri[:i]
+	}
+	return uri[:i+1] + policy.redactValues(query, policy.QueryParameters).Encode()
+}
+
+// RedactBody returns the passed request body with the field values redacted.
+// Bodies that are not JSON or form encoded are dropped.
+func (policy *RedactionPolicy) RedactBody(contentType, body string) string {
+	if "" == body {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case contentTypeForm:
+		values, err := url.ParseQuery(body)
+		if err != nil {
+			return ""
+		}
+		return policy.redactValues(values, policy.BodyFields).Encode()
+	case contentTypeJSON:
+		var value interface{}
+		if err := json.Unmarshal([]byte(body), &value); err != nil {
+			return ""
+		}
+		b, _ := json.Marshal(policy.redactJSON(value))
+		return string(b)
+	}
+	return ""
+}
+
+func (policy *RedactionPolicy) redactValues(values url.Values, names []string) url.Values {
+	for name := range values {
+		if containsFold(names, name) {
+			values[name] = []string{Redacted}
+		}
+	}
+	return values
+}
+
+func (policy *RedactionPolicy) redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if containsFold(policy.BodyFields, key) {
+				v[key] = Redacted
+			} else {
+				v[key] = policy.redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = policy.redactJSON(element)
+		}
+	}
+	return value
+}
+
+// ExcludeFromAccessLog stops requests whose path begins with the passed route
+// prefix being access logged. The health check is excluded by
+// CreateRESTServer.
+func (server *RESTServer) ExcludeFromAccessLog(prefix string) error {
+	segments, err := splitPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	server.accessLogExclusions = append(server.accessLogExclusions, segments)
+	return nil
+}
+
+// logAccess passes an entry for the completed request to the AccessLogger,
+// unless the path is excluded or the request is not sampled.
+func (server *RESTServer) logAccess(context *Context) {
+	path := strings.Split(cleanPath(context.URI), Slash)
+	for _, prefix := range server.accessLogExclusions {
+		if hasPrefix(path, prefix) {
+			return
+		}
+	}
+	if server.AccessLogSampleRate > 0 && context.Status() < http.StatusBadRequest &&
+		rand.Float64() >= server.AccessLogSampleRate {
+		return
+	}
+	logger := server.AccessLogger
+	if nil == logger {
+		logger = defaultAccessLogger
+	}
+	policy := server.AccessLogRedaction
+	if nil == policy {
+		policy = DefaultRedactionPolicy
+	}
+	start := context.start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	entry := &AccessLogEntry{
+		Time:       start,
+		RequestID:  context.RequestID,
+		RemoteAddr: context.Request.RemoteAddr,
+		Method:     context.Request.Method,
+		URI:        policy.RedactURI(context.Request.RequestURI),
+		Proto:      context.Request.Proto,
+		Header:     policy.RedactHeader(context.Request.Header),
+		Status:     context.Status(),
+		Size:       context.responseWriter().Size(),
+		Latency:    time.Since(start),
+		UserAgent:  context.Request.UserAgent(),
+		Referer:    context.Request.Referer(),
+		Body:       policy.RedactBody(context.Request.Header.Get(contentTypeHeader), context.Body),
+	}
+	if context.HasError() {
+		entry.ErrorCode = context.Error.Code
+	}
+	logger.Log(entry)
+}
+
+var defaultAccessLogger = &JSONAccessLogger{}
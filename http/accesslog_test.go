@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type recordingAccessLogger struct {
+	entries []*AccessLogEntry
+}
+
+func (logger *recordingAccessLogger) Log(entry *AccessLogEntry) {
+	logger.entries = append(logger.entries, entry)
+}
+
+type readingController struct {
+	TestController
+}
+
+func (controller *readingController) Post(context *Context) {
+	controller.MethodCalled = http.MethodPost
+	context.Read()
+	context.SetResponse("created", http.StatusCreated)
+}
+
+func createAccessLogServer() (*RESTServer, *recordingAccessLogger) {
+	logger := &recordingAccessLogger{}
+	server := CreateRESTServer(":8080", nil)
+	server.Router.AddRoute("api/login", &readingController{TestController: NewTestController("Login")})
+	server.Router.AddRoute(HealthCheckRoute, &readingController{TestController: NewTestController("Health")})
+	server.AccessLogger = logger
+	return server, logger
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestAccessLogEntry(t *testing.T) {
+	assert := assert.New(t)
+	server, logger := createAccessLogServer()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/login?user=bob&token=abc",
+		strings.NewReader(`{"user":"bob","password":"hunter2","nested":[{"Secret":"s"}]}`))
+	request.Header.Set(contentTypeHeader, contentTypeJSON)
+	request.Header.Set("Authorization", "Bearer abc")
+	request.Header.Set("X-Api-Key", "key")
+	request.Header.Set(DefaultRequestIDHeader, "logged")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	if assert.Len(logger.entries, 1) {
+		entry := logger.entries[0]
+		assert.Equal("logged", entry.RequestID)
+		assert.Equal(http.MethodPost, entry.Method)
+		assert.Equal(http.StatusCreated, entry.Status)
+		assert.Equal(recorder.Body.Len(), entry.Size)
+		assert.True(entry.Latency >= 0)
+		assert.Equal("/api/login?token=%5BREDACTED%5D&user=bob", entry.URI)
+		assert.Equal(http.Header{contentTypeHeader: {contentTypeJSON}}, entry.Header)
+		assert.Equal("Bearer abc", request.Header.Get("Authorization"))
+		assert.Equal(`{"nested":[{"Secret":"[REDACTED]"}],"password":"[REDACTED]","user":"bob"}`, entry.Body)
+	}
+}
+
+func TestAccessLogErrorCode(t *testing.T) {
+	assert := assert.New(t)
+	server, logger := createAccessLogServer()
+	serve(server, http.MethodGet, "/does/not/exist")
+	if assert.Len(logger.entries, 1) {
+		assert.Equal(http.StatusNotFound, logger.entries[0].Status)
+		assert.Equal(qerror.NotFound, logger.entries[0].ErrorCode)
+	}
+}
+
+func TestAccessLogExclusions(t *testing.T) {
+	assert := assert.New(t)
+	server, logger := createAccessLogServer()
+	serve(server, http.MethodGet, "/"+HealthCheckRoute)
+	assert.Empty(logger.entries)
+
+	assert.NoError(server.ExcludeFromAccessLog("api"))
+	assert.Error(server.ExcludeFromAccessLog("/api"))
+	serve(server, http.MethodGet, "/api/login")
+	assert.Empty(logger.entries)
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	assert := assert.New(t)
+	server, logger := createAccessLogServer()
+	server.AccessLogSampleRate = 0.000001
+	for i := 0; i < 10; i++ {
+		serve(server, http.MethodGet, "/api/login")
+	}
+	assert.Empty(logger.entries)
+
+	// errors are always logged
+	serve(server, http.MethodGet, "/does/not/exist")
+	assert.Len(logger.entries, 1)
+}
+
+func TestRedactHeader(t *testing.T) {
+	assert := assert.New(t)
+	policy := &RedactionPolicy{LoggedHeaders: []string{"authorization", "Accept"}, Headers: []string{"Authorization"}}
+	header := http.Header{"Authorization": {"Bearer abc"}, "Accept": {"*/*"}, "X-Api-Key": {"key"}}
+	assert.Equal(http.Header{"Authorization": {Redacted}, "Accept": {"*/*"}}, policy.RedactHeader(header))
+	assert.Empty((&RedactionPolicy{}).RedactHeader(header))
+}
+
+func TestRedactBody(t *testing.T) {
+	assert := assert.New(t)
+	policy := DefaultRedactionPolicy
+	assert.Equal("password=%5BREDACTED%5D&user=bob", policy.RedactBody(contentTypeForm, "user=bob&password=x"))
+	assert.Equal(`{"token":"[REDACTED]"}`, policy.RedactBody(contentTypeJSON+"; charset=utf-8", `{"token":"x"}`))
+	assert.Equal("", policy.RedactBody(contentTypeJSON, "not json"))
+	assert.Equal("", policy.RedactBody("text/plain", "password=x"))
+}
+
+func TestJSONAccessLogger(t *testing.T) {
+	assert := assert.New(t)
+	buffer := &bytes.Buffer{}
+	logger := &JSONAccessLogger{Writer: buffer}
+	logger.Log(&AccessLogEntry{
+		Time:      time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		RequestID: "req-1",
+		Method:    http.MethodGet,
+		URI:       "/api",
+		Status:    http.StatusOK,
+		Size:      12,
+		Latency:   1500 * time.Microsecond,
+	})
+	assert.True(strings.HasSuffix(buffer.String(), "\n"))
+	entry := map[string]interface{}{}
+	assert.NoError(json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal("2018-10-01T12:00:00Z", entry["time"])
+	assert.Equal("req-1", entry["request_id"])
+	assert.Equal(float64(200), entry["status"])
+	assert.Equal(float64(12), entry["size"])
+	assert.Equal(1.5, entry["latency_ms"])
+}
+
+func TestCLFAccessLogger(t *testing.T) {
+	assert := assert.New(t)
+	entry := &AccessLogEntry{
+		Time:       time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		RemoteAddr: "192.0.2.1:1234",
+		Method:     http.MethodGet,
+		URI:        "/api",
+		Proto:      "HTTP/1.1",
+		Status:     http.StatusOK,
+		Size:       12,
+		UserAgent:  "test",
+	}
+	buffer := &bytes.Buffer{}
+	(&CLFAccessLogger{Writer: buffer}).Log(entry)
+	assert.Equal("192.0.2.1 - - [01/Oct/2018:12:00:00 +0000] \"GET /api HTTP/1.1\" 200 12\n", buffer.String())
+
+	buffer.Reset()
+	(&CLFAccessLogger{Writer: buffer, Combined: true}).Log(entry)
+	assert.Equal("192.0.2.1 - - [01/Oct/2018:12:00:00 +0000] \"GET /api HTTP/1.1\" 200 12 \"-\" \"test\"\n",
+		buffer.String())
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+
+	"github.com/Kasita-Inc/gadget/stringutil"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+const (
+	uriTag     = "uri"
+	headerTag  = "header"
+	cookieTag  = "cookie"
+	defaultTag = "default"
+)
+
+// bindSources are the tags naming the parts of the request bound by Bind,
+// in the order they are bound.
+var bindSources = []string{uriTag, queryTag, headerTag, cookieTag}
+
+// Bind populates the target, a pointer to a struct, from the request. Fields
+// are bound from the source named by their tag:
+//
+//	type WidgetQuery struct {
+//		ID      string `uri:"id,required"`
+//		Limit   int    `query:"limit" default:"20"`
+//		Version string `header:"X-Widget-Version"`
+//		Session string `cookie:"session"`
+//		Name    string `json:"name"`
+//	}
+//
+// Fields without a uri, query, header or cookie tag are decoded from the body
+// by ReadObject if the request has one, tagged fields are never set from the
+// body. The 'required' tag option reports a
+// FieldError if the source has no value for the field, and the 'default' tag
+// sets fields that were not supplied. The bound target is then validated, see
+// Validate. All the FieldErrors are aggregated in a validation error on the
+// Context, which is returned.
+func (context *Context) Bind(target interface{}) error {
+	v, err := structValue(target)
+	if nil != err {
+		context.setBindError(err)
+		return err
+	}
+
+	// body fields are set to their default before decoding so that values in
+	// the body, including zero values, replace it
+	languages := context.languages()
+	eachBoundField(v, func(field reflect.StructField, value reflect.Value) {
+		source, name, _ := boundSource(field)
+		if defaultValue, ok := field.Tag.Lookup(defaultTag); ok && "" == source && isZero(value) {
+			context.bindDefault(value, field, name, defaultValue, languages)
+		}
+	})
+	if context.hasBody() {
+		// fields bound from the other sources are not decoded from the body,
+		// they are cleared for decoding and then restored
+		tagged, saved := []reflect.Value{}, []reflect.Value{}
+		eachBoundField(v, func(field reflect.StructField, value reflect.Value) {
+			if source, _, _ := boundSource(field); "" != source {
+				tagged = append(tagged, value)
+				saved = append(saved, reflect.New(value.Type()).Elem())
+				saved[len(saved)-1].Set(value)
+				value.Set(reflect.Zero(value.Type()))
+			}
+		})
+		err = context.readObject(target)
+		for i, value := range tagged {
+			value.Set(saved[i])
+		}
+		if _, noContent := err.(*NoContentError); noContent {
+			err = nil
+		}
+		if _, invalid := err.(FieldErrors); nil != err && !invalid {
+			return err
+		}
+	}
+
+	binders := map[string]*binder{}
+	for _, source := range bindSources {
+		b := newBinder(context.sourceValues(source, v), source)
+		b.taggedOnly = true
+		b.languages = languages
+		b.bindStruct(v, "")
+		binders[source] = b
+		for _, fieldError := range b.errors {
+			context.AddError(fieldError)
+		}
+	}
+
+	eachBoundField(v, func(field reflect.StructField, value reflect.Value) {
+		source, name, options := boundSource(field)
+		b, ok := binders[source]
+		if !ok || b.hasKey(canonicalKey(name)) {
+			return
+		}
+		if defaultValue, ok := field.Tag.Lookup(defaultTag); ok {
+			context.bindDefault(value, field, name, defaultValue, languages)
+		} else if hasOption(options, requiredOption) {
+			context.AddError(newFieldError(qerror.CannotBeBlank, name, languages, name))
+		}
+	})
+
+	if context.HasError() {
+		return context.Error
+	}
+	return context.validate(target)
+}
+
+// bindDefault sets the field to the passed default value.
+func (context *Context) bindDefault(value reflect.Value, field reflect.StructField, name, defaultValue string,
+	languages []string) {
+	b := &binder{values: url.Values{name: {defaultValue}}, languages: languages}
+	b.bindField(value, name, field.Tag.Get(timeFormatTag))
+	for _, fieldError := range b.errors {
+		context.AddError(fieldError)
+	}
+}
+
+// hasBody checks if the request has a body to bind. Bodies of unknown length
+// are peeked at to check they are not empty.
+func (context *Context) hasBody() bool {
+	if context.bodyRead {
+		return "" != context.Body
+	}
+	body := context.Request.Body
+	if nil == body || http.NoBody == body || 0 == context.Request.ContentLength {
+		return false
+	}
+	if context.Request.ContentLength > 0 {
+		return true
+	}
+	peeked := make([]byte, 1)
+	n, err := io.ReadFull(body, peeked)
+	if 0 == n && io.EOF == err {
+		context.Request.Body = http.NoBody
+		return false
+	}
+	context.Request.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(peeked[:n]), body), Closer: body}
+	return true
+}
+
+// peekedBody is a request body that has had its first byte read.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// sourceValues returns the values of the request for the passed source.
+func (context *Context) sourceValues(source string, v reflect.Value) url.Values {
+	values := url.Values{}
+	switch source {
+	case uriTag:
+		for name, value := range context.URIParameters {
+			values.Set(name, value)
+		}
+	case queryTag:
+		return context.URLParameters
+	case headerTag:
+		eachBoundField(v, func(field reflect.StructField, value reflect.Value) {
+			if fieldSource, name, _ := boundSource(field); headerTag == fieldSource {
+				values[name] = context.Request.Header[textproto.CanonicalMIMEHeaderKey(name)]
+			}
+		})
+	case cookieTag:
+		for _, cookie := range context.Request.Cookies() {
+			values.Add(cookie.Name, cookie.Value)
+		}
+	}
+	return values
+}
+
+// eachBoundField calls the function for each exported field of the struct,
+// including the fields of embedded structs.
+func eachBoundField(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous && reflect.Struct == field.Type.Kind() {
+			eachBoundField(v.Field(i), fn)
+			continue
+		}
+		if "" == field.PkgPath {
+			fn(field, v.Field(i))
+		}
+	}
+}
+
+// boundSource returns the source the field is bound from by Bind, or an empty
+// string for fields bound from the body, and its name and tag options.
+func boundSource(field reflect.StructField) (string, string, []string) {
+	for _, source := range bindSources {
+		if _, ok := field.Tag.Lookup(source); ok {
+			name, options := taggedName(field, source)
+			return source, name, options
+		}
+	}
+	return "", stringutil.Underscore(field.Name), nil
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
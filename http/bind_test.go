@@ -0,0 +1,153 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type widgetBinding struct {
+	ID       int      `uri:"id,required"`
+	Limit    int      `query:"limit" default:"20"`
+	Tags     []string `query:"tag"`
+	Version  string   `header:"X-Widget-Version,required"`
+	Session  string   `cookie:"session"`
+	Name     string   `json:"name"`
+	Color    string   `json:"color" default:"blue"`
+	Override string   `json:"override" query:"override"`
+}
+
+// createBindContext creates a Context for a request to the URI with the
+// passed URI parameters and JSON body.
+func createBindContext(uri string, parameters map[string]string, body string) *Context {
+	method := http.MethodPost
+	if "" == body {
+		method = http.MethodGet
+	}
+	context := createRequestContext(nil, newRequest(method, uri, body, contentTypeHeader, MediaTypeJSON))
+	context.URIParameters = parameters
+	return context
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+	context := createBindContext("/widgets/7?tag=a&tag=b&override=query", map[string]string{"id": "7"},
+		`{"name":"bolt","override":"body"}`)
+	context.Request.Header.Set("x-widget-version", "v2")
+	context.Request.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	binding := &widgetBinding{}
+	assert.NoError(context.Bind(binding))
+	assert.Equal(&widgetBinding{
+		ID:       7,
+		Limit:    20,
+		Tags:     []string{"a", "b"},
+		Version:  "v2",
+		Session:  "abc",
+		Name:     "bolt",
+		Color:    "blue",
+		Override: "query",
+	}, binding)
+}
+
+func TestBindIgnoresBodyForTaggedFields(t *testing.T) {
+	assert := assert.New(t)
+	context := createBindContext("/widgets/7", map[string]string{"id": "7"},
+		`{"name":"bolt","Version":"body","ID":9,"override":"body","Session":"body"}`)
+
+	binding := &widgetBinding{Session: "preset"}
+	assert.Error(context.Bind(binding))
+	assert.Equal(map[string]string{"X-Widget-Version": qerror.CannotBeBlank}, fieldErrorCodes(context.Error))
+	assert.Equal(7, binding.ID)
+	assert.Empty(binding.Version)
+	assert.Empty(binding.Override)
+	assert.Equal("preset", binding.Session)
+	assert.Equal("bolt", binding.Name)
+}
+
+func TestBindWithoutBody(t *testing.T) {
+	assert := assert.New(t)
+	context := createBindContext("/widgets/7?limit=5", map[string]string{"id": "7"}, "")
+	context.Request.Header.Set("X-Widget-Version", "v1")
+
+	binding := &widgetBinding{Color: "red"}
+	assert.NoError(context.Bind(binding))
+	assert.Equal(5, binding.Limit)
+	assert.Equal("red", binding.Color)
+	assert.Empty(binding.Name)
+}
+
+func TestBindBodyZeroValues(t *testing.T) {
+	assert := assert.New(t)
+	context := createBindContext("/widgets/7", map[string]string{"id": "7"}, `{"name":"bolt","color":""}`)
+	context.Request.Header.Set("X-Widget-Version", "v1")
+
+	// values supplied in the body are not replaced by their default
+	binding := &widgetBinding{}
+	assert.NoError(context.Bind(binding))
+	assert.Empty(binding.Color)
+	assert.Equal(20, binding.Limit)
+}
+
+func TestBindEmptyBody(t *testing.T) {
+	assert := assert.New(t)
+	request := newRequest(http.MethodPost, "/widgets/7", "", "X-Widget-Version", "v1")
+	request.Body = ioutil.NopCloser(strings.NewReader(""))
+	request.ContentLength = -1
+	context := createRequestContext(nil, request)
+	context.URIParameters = map[string]string{"id": "7"}
+
+	// a body of unknown length that is empty is not decoded
+	assert.False(context.hasBody())
+	binding := &widgetBinding{}
+	assert.NoError(context.Bind(binding))
+	assert.Equal(7, binding.ID)
+	assert.Equal("blue", binding.Color)
+
+	request = chunkedRequest(`{"name":"bolt"}`)
+	request.Header.Set(contentTypeHeader, MediaTypeJSON)
+	request.Header.Set("X-Widget-Version", "v1")
+	context = createRequestContext(nil, request)
+	context.URIParameters = map[string]string{"id": "7"}
+	assert.True(context.hasBody())
+	binding = &widgetBinding{}
+	assert.NoError(context.Bind(binding))
+	assert.Equal("bolt", binding.Name)
+}
+
+func TestBindErrors(t *testing.T) {
+	assert := assert.New(t)
+	context := createBindContext("/widgets/x?limit=many", map[string]string{"id": "x"}, "")
+
+	err := context.Bind(&widgetBinding{})
+	assert.Equal(context.Error, err)
+	assert.Equal(http.StatusBadRequest, context.Status())
+	assert.Equal(qerror.ValidationError, context.Error.Code)
+	assert.Equal(map[string]string{
+		"id":               qerror.InvalidValue,
+		"limit":            qerror.InvalidValue,
+		"X-Widget-Version": qerror.CannotBeBlank,
+	}, fieldErrorCodes(context.Error))
+
+	context = createBindContext("/widgets", map[string]string{}, `{"name":`)
+	assert.Error(context.Bind(&widgetBinding{}))
+	assert.Equal(http.StatusNotAcceptable, context.Status())
+	assert.Equal(qerror.ValidationError, context.Error.Code)
+	assert.Contains(context.Error.Message, "unexpected end of JSON input")
+
+	context = createBindContext("/widgets", map[string]string{}, "")
+	assert.Error(context.Bind(widgetBinding{}))
+}
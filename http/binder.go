@@ -0,0 +1,371 @@
+package http
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kasita-Inc/gadget/stringutil"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+const (
+	queryTag      = "query"
+	timeFormatTag = "time_format"
+	keySeparator  = "."
+)
+
+// MaxBindIndex is the largest index bound to a slice from keys such as
+// 'items[0].name', larger indexes are reported as invalid values so clients
+// cannot allocate arbitrarily large slices.
+var MaxBindIndex = 1000
+
+// timeLayouts are tried in order when binding a time.Time field without a
+// 'time_format' tag.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldErrors is returned when values cannot be bound to the fields of a
+// target, with an error for each field.
+type FieldErrors []qerror.FieldError
+
+func (errs FieldErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, " ")
+}
+
+// newFieldError creates a FieldError for the field with the message template
+// registered for the code, see qerror.Register, localized for the passed
+// languages and formatted with the passed args.
+func newFieldError(code, field string, languages []string, args ...interface{}) qerror.FieldError {
+	return qerror.FieldError{
+		Code:    code,
+		Message: qerror.Format(qerror.Localize(code, languages...), args...),
+		Field:   field,
+	}
+}
+
+// taggedName returns the name of the value bound to the struct field from
+// the passed tag, or the underscored field name, and the tag options.
+func taggedName(field reflect.StructField, tag string) (string, []string) {
+	options := strings.Split(field.Tag.Get(tag), ",")
+	name := strings.TrimSpace(options[0])
+	if "" == name {
+		name = stringutil.Underscore(field.Name)
+	}
+	return name, options[1:]
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if option == strings.TrimSpace(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalKey converts bracketed keys such as 'filter[status]' and
+// 'items[0][name]' to the dotted form 'filter.status' and 'items.0.name'.
+// Empty brackets such as 'tags[]' are removed.
+func canonicalKey(key string) string {
+	key = strings.Replace(key, "[]", "", -1)
+	key = strings.Replace(key, "]", "", -1)
+	return strings.Replace(key, "[", keySeparator, -1)
+}
+
+// binder binds url.Values to the fields of a struct.
+type binder struct {
+	tag    string
+	values url.Values
+	errors FieldErrors
+	// taggedOnly binds only the top level fields with the tag.
+	taggedOnly bool
+	// languages localize the messages of the FieldErrors.
+	languages []string
+}
+
+// newBinder creates a binder for the values, keyed by their canonical keys.
+func newBinder(values url.Values, tag string) *binder {
+	b := &binder{tag: tag, values: url.Values{}}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// sorting places 'tags' before 'tags[]' so values keep a stable order
+	sort.Strings(keys)
+	for _, key := range keys {
+		canonical := canonicalKey(key)
+		b.values[canonical] = append(b.values[canonical], values[key]...)
+	}
+	return b
+}
+
+// structValue returns the struct the target points to.
+func structValue(target interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if reflect.Ptr != v.Kind() || v.IsNil() || reflect.Struct != v.Elem().Kind() {
+		return v, fmt.Errorf("cannot bind values to %T, a pointer to a struct is required", target)
+	}
+	return v.Elem(), nil
+}
+
+// bindValues binds the values to the fields of the target, named by the
+// passed tag or the underscored field name. Nested struct, map, and slice
+// fields are bound from keys such as 'filter[status]' or 'items[0].name'.
+// Values that cannot be coerced to the type of their field are returned as
+// FieldErrors, with messages localized for the passed languages.
+func bindValues(values url.Values, target interface{}, tag string, languages ...string) error {
+	v, err := structValue(target)
+	if nil != err {
+		return err
+	}
+	b := newBinder(values, tag)
+	b.languages = languages
+	b.bindStruct(v, "")
+	if len(b.errors) > 0 {
+		return b.errors
+	}
+	return nil
+}
+
+func joinKey(prefix, name string) string {
+	if "" == prefix {
+		return name
+	}
+	return prefix + keySeparator + name
+}
+
+// hasKey checks if any non-empty value is bound to the key or a key nested in
+// it.
+func (b *binder) hasKey(key string) bool {
+	for k, values := range b.values {
+		if k != key && !strings.HasPrefix(k, key+keySeparator) {
+			continue
+		}
+		for _, value := range values {
+			if !stringutil.IsEmpty(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *binder) bindStruct(v reflect.Value, prefix string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous && "" == field.Tag.Get(b.tag) && reflect.Struct == field.Type.Kind() {
+			b.bindStruct(v.Field(i), prefix)
+			continue
+		}
+		if "" != field.PkgPath || formFileType == field.Type || formFilesType == field.Type {
+			continue
+		}
+		if b.taggedOnly && "" == prefix && "" == field.Tag.Get(b.tag) {
+			continue
+		}
+		name, _ := taggedName(field, b.tag)
+		if "-" == name {
+			continue
+		}
+		b.bindField(v.Field(i), joinKey(prefix, name), field.Tag.Get(timeFormatTag))
+	}
+}
+
+func (b *binder) bindField(v reflect.Value, key, timeFormat string) {
+	if !b.hasKey(key) {
+		return
+	}
+	if isScalar(v.Type()) {
+		if value := b.values.Get(key); !stringutil.IsEmpty(value) {
+			b.setScalar(v, key, value, timeFormat)
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		b.bindField(v.Elem(), key, timeFormat)
+	case reflect.Struct:
+		b.bindStruct(v, key)
+	case reflect.Slice:
+		b.bindSlice(v, key, timeFormat)
+	case reflect.Map:
+		b.bindMap(v, key, timeFormat)
+	}
+}
+
+// bindSlice binds repeated values such as 'tags=a&tags=b' to a slice of
+// scalars, and indexed values such as 'items[0].name' to any slice.
+func (b *binder) bindSlice(v reflect.Value, key, timeFormat string) {
+	elementType := v.Type().Elem()
+	if isScalar(elementType) && !stringutil.IsEmpty(b.values.Get(key)) {
+		slice := reflect.MakeSlice(v.Type(), 0, len(b.values[key]))
+		for _, value := range b.values[key] {
+			element := reflect.New(elementType).Elem()
+			b.setScalar(element, key, value, timeFormat)
+			slice = reflect.Append(slice, element)
+		}
+		v.Set(slice)
+		return
+	}
+
+	length := -1
+	for k := range b.values {
+		if !strings.HasPrefix(k, key+keySeparator) {
+			continue
+		}
+		segment := strings.SplitN(k[len(key)+1:], keySeparator, 2)[0]
+		index, err := strconv.Atoi(segment)
+		if numError, ok := err.(*strconv.NumError); ok && strconv.ErrRange == numError.Err || index > MaxBindIndex {
+			b.errors = append(b.errors, newFieldError(qerror.InvalidValue, joinKey(key, segment), b.languages,
+				segment, key))
+			continue
+		}
+		if nil == err && index >= 0 && index > length {
+			length = index
+		}
+	}
+	if length < 0 {
+		return
+	}
+	slice := reflect.MakeSlice(v.Type(), length+1, length+1)
+	reflect.Copy(slice, v)
+	for i := 0; i <= length; i++ {
+		b.bindField(slice.Index(i), joinKey(key, strconv.Itoa(i)), timeFormat)
+	}
+	v.Set(slice)
+}
+
+// bindMap binds values such as 'filter[status]=open' to a map keyed by
+// string.
+func (b *binder) bindMap(v reflect.Value, key, timeFormat string) {
+	if reflect.String != v.Type().Key().Kind() {
+		return
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	mapKeys := map[string]bool{}
+	for k := range b.values {
+		if strings.HasPrefix(k, key+keySeparator) {
+			mapKeys[strings.SplitN(k[len(key)+1:], keySeparator, 2)[0]] = true
+		}
+	}
+	for mapKey := range mapKeys {
+		element := reflect.New(v.Type().Elem()).Elem()
+		b.bindField(element, joinKey(key, mapKey), timeFormat)
+		v.SetMapIndex(reflect.ValueOf(mapKey).Convert(v.Type().Key()), element)
+	}
+}
+
+// isScalar checks if a field of the type is bound from a single value.
+func isScalar(t reflect.Type) bool {
+	if timeType == t || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func (b *binder) setScalar(v reflect.Value, key, value, timeFormat string) {
+	if err := setScalar(v, value, timeFormat); nil != err {
+		b.errors = append(b.errors, newFieldError(qerror.InvalidValue, key, b.languages, value, key))
+	}
+}
+
+// setScalar coerces the value to the type of the passed field.
+func setScalar(v reflect.Value, value, timeFormat string) error {
+	if timeType == v.Type() {
+		return setTime(v, value, timeFormat)
+	}
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	if durationType == v.Type() {
+		d, err := time.ParseDuration(value)
+		if nil != err {
+			return fmt.Errorf("expected a duration")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "on", "yes":
+			v.SetBool(true)
+		case "off", "no":
+			v.SetBool(false)
+		default:
+			parsed, err := strconv.ParseBool(value)
+			if nil != err {
+				return fmt.Errorf("expected a boolean")
+			}
+			v.SetBool(parsed)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if nil != err {
+			return fmt.Errorf("expected an integer")
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if nil != err {
+			return fmt.Errorf("expected a positive integer")
+		}
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, v.Type().Bits())
+		if nil != err {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(parsed)
+	}
+	return nil
+}
+
+// setTime parses the value using the passed layout, or the timeLayouts and
+// unix seconds if no layout is passed.
+func setTime(v reflect.Value, value, layout string) error {
+	layouts := timeLayouts
+	if "" != layout {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); nil == err {
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+	if "" == layout {
+		if seconds, err := strconv.ParseInt(value, 10, 64); nil == err {
+			v.Set(reflect.ValueOf(time.Unix(seconds, 0).UTC()))
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a time")
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type boundItem struct {
+	Name     string
+	Quantity int
+}
+
+type boundAudit struct {
+	CreatedBy string `form:"created_by" query:"author"`
+}
+
+type boundRequest struct {
+	boundAudit
+	Serial   uint64
+	Count    *int
+	Ratio    float32
+	Enabled  bool
+	Tags     []string
+	Since    time.Time
+	Day      time.Time `time_format:"02/01/2006"`
+	Timeout  time.Duration
+	Name     string            `form:"display_name" query:"q"`
+	Filter   map[string]string `form:"filter"`
+	Items    []boundItem
+	Owner    *boundItem
+	Ignored  string `form:"-" query:"-"`
+	internal string
+}
+
+func bindQuery(query string, tag string) (*boundRequest, error) {
+	values, _ := url.ParseQuery(query)
+	target := &boundRequest{}
+	return target, bindValues(values, target, tag)
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestBindValues(t *testing.T) {
+	assert := assert.New(t)
+	target, err := bindQuery("serial=7&count=3&ratio=0.5&enabled=on&tags=a&tags[]=b&since=2018-06-01T10:00:00Z"+
+		"&day=15/06/2018&timeout=1m30s&display_name=widget&created_by=admin&ignored=x&internal=x", formTag)
+	assert.NoError(err)
+	assert.Equal(uint64(7), target.Serial)
+	if assert.NotNil(target.Count) {
+		assert.Equal(3, *target.Count)
+	}
+	assert.Equal(float32(0.5), target.Ratio)
+	assert.True(target.Enabled)
+	assert.Equal([]string{"a", "b"}, target.Tags)
+	assert.Equal(time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC), target.Since)
+	assert.Equal(time.Date(2018, 6, 15, 0, 0, 0, 0, time.UTC), target.Day)
+	assert.Equal(90*time.Second, target.Timeout)
+	assert.Equal("widget", target.Name)
+	assert.Equal("admin", target.CreatedBy)
+	assert.Empty(target.Ignored)
+	assert.Empty(target.internal)
+}
+
+func TestBindValuesTags(t *testing.T) {
+	assert := assert.New(t)
+	target, err := bindQuery("q=widget&author=admin&display_name=other", queryTag)
+	assert.NoError(err)
+	assert.Equal("widget", target.Name)
+	assert.Equal("admin", target.CreatedBy)
+}
+
+func TestBindValuesTimeFormats(t *testing.T) {
+	assert := assert.New(t)
+	target, err := bindQuery("since=2018-06-01", formTag)
+	assert.NoError(err)
+	assert.Equal(time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), target.Since)
+
+	target, err = bindQuery("since=1527847200", formTag)
+	assert.NoError(err)
+	assert.Equal(time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC), target.Since)
+}
+
+func TestBindValuesNested(t *testing.T) {
+	assert := assert.New(t)
+	target, err := bindQuery("filter[status]=open&filter[owner]=me&items[1].name=nut&items[0][name]=bolt"+
+		"&items[0][quantity]=2&owner.name=roundy", formTag)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"status": "open", "owner": "me"}, target.Filter)
+	assert.Equal([]boundItem{{Name: "bolt", Quantity: 2}, {Name: "nut"}}, target.Items)
+	assert.Equal(&boundItem{Name: "roundy"}, target.Owner)
+	assert.Nil(target.Count)
+}
+
+func TestBindValuesFieldErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := bindQuery("serial=-1&ratio=half&enabled=maybe&since=yesterday&items[0].quantity=two&tags=a", formTag)
+	fieldErrors, ok := err.(FieldErrors)
+	if assert.True(ok) && assert.Len(fieldErrors, 5) {
+		fields := map[string]string{}
+		for _, fieldError := range fieldErrors {
+			fields[fieldError.Field] = fieldError.Code
+		}
+		assert.Equal(map[string]string{
+			"serial":           qerror.InvalidValue,
+			"ratio":            qerror.InvalidValue,
+			"enabled":          qerror.InvalidValue,
+			"since":            qerror.InvalidValue,
+			"items.0.quantity": qerror.InvalidValue,
+		}, fields)
+	}
+
+	assert.Error(bindValues(url.Values{}, boundRequest{}, formTag))
+}
+
+func TestBindValuesIndexLimit(t *testing.T) {
+	assert := assert.New(t)
+	target, err := bindQuery("items[1000].name=bolt", formTag)
+	assert.NoError(err)
+	assert.Len(target.Items, 1001)
+
+	for _, index := range []string{"50000000", "9223372036854775806", "99999999999999999999"} {
+		target, err = bindQuery("items[0].name=bolt&items["+index+"].name=nut", formTag)
+		fieldErrors, ok := err.(FieldErrors)
+		if assert.True(ok) && assert.Len(fieldErrors, 1) {
+			assert.Equal(qerror.InvalidValue, fieldErrors[0].Code)
+			assert.Equal("items."+index, fieldErrors[0].Field)
+		}
+		assert.Equal([]boundItem{{Name: "bolt"}}, target.Items)
+	}
+}
+
+func TestReadQueryParams(t *testing.T) {
+	assert := assert.New(t)
+	values, _ := url.ParseQuery("q=bolt&count=x&filter[status]=open")
+	context := &Context{URLParameters: values}
+
+	target := &boundRequest{}
+	assert.Error(context.ReadQueryParams(target))
+	assert.Equal("bolt", target.Name)
+	assert.Equal(map[string]string{"status": "open"}, target.Filter)
+	assert.Equal(http.StatusBadRequest, context.Status())
+	assert.Equal(map[string]string{"count": qerror.InvalidValue}, fieldErrorCodes(context.Error))
+}
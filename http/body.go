@@ -0,0 +1,163 @@
+package http
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Kasita-Inc/gadget/errors"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+// ErrBodyTooLarge is returned when reading a request body larger than the
+// MaxBodySize of the RESTServer.
+var ErrBodyTooLarge = errors.New("request body exceeds the maximum size")
+
+// maxBodyReader fails with ErrBodyTooLarge once more than max bytes have
+// been read.
+type maxBodyReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (reader *maxBodyReader) Read(p []byte) (int, error) {
+	if reader.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > reader.remaining+1 {
+		p = p[:reader.remaining+1]
+	}
+	n, err := reader.ReadCloser.Read(p)
+	reader.remaining -= int64(n)
+	if reader.remaining < 0 {
+		return n + int(reader.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// limitBody enforces the MaxBodySize on the request, rejecting it before it
+// is handled if the declared Content-Length is too large.
+func (server *RESTServer) limitBody(context *Context) {
+	if server.MaxBodySize <= 0 || nil == context.Request.Body || http.NoBody == context.Request.Body {
+		return
+	}
+	context.Request.Body = &maxBodyReader{ReadCloser: context.Request.Body, remaining: server.MaxBodySize}
+	if context.Request.ContentLength > server.MaxBodySize && !context.HasError() {
+		context.bodyTooLarge()
+	}
+}
+
+// bodyTooLarge fails the request as its body exceeds the MaxBodySize of the
+// server.
+func (context *Context) bodyTooLarge() {
+	var maxBodySize int64
+	if nil != context.server {
+		maxBodySize = context.server.MaxBodySize
+	}
+	context.Fail(qerror.RequestTooLarge, maxBodySize)
+}
+
+// setBodyError sets the error for a failure reading the request body,
+// reporting bodies that are too large with a 413 status. Readers such as
+// multipart.Reader may wrap ErrBodyTooLarge, or fail parsing what was read
+// before the limit was hit.
+func (context *Context) setBodyError(err error) {
+	if stderrors.Is(err, ErrBodyTooLarge) || context.bodyLimitExceeded() {
+		context.bodyTooLarge()
+		return
+	}
+	context.setBindError(err)
+}
+
+// bodyLimitExceeded checks if more than the MaxBodySize of the server has
+// been read from the request body.
+func (context *Context) bodyLimitExceeded() bool {
+	var body io.Closer = context.Request.Body
+	if peeked, ok := body.(*peekedBody); ok {
+		body = peeked.Closer
+	}
+	reader, ok := body.(*maxBodyReader)
+	return ok && reader.remaining < 0
+}
+
+// BodyReader returns a reader streaming the request body, limited to the
+// MaxBodySize of the server. It should not be used together with Read or
+// ReadObject, which read the entire body.
+func (context *Context) BodyReader() io.Reader {
+	if nil == context.Request.Body {
+		return http.NoBody
+	}
+	return context.Request.Body
+}
+
+// readAll reads a request body of unknown length such as a chunked body.
+func (context *Context) readAll() ([]byte, error) {
+	body, err := ioutil.ReadAll(context.BodyReader())
+	if nil == err && 0 == len(body) {
+		return nil, NewNoContentError(context.URI, context.Method)
+	}
+	return body, err
+}
+
+// JSONArrayDecoder decodes the elements of a JSON array request body one at
+// a time, so large arrays are not held in memory.
+type JSONArrayDecoder struct {
+	context *Context
+	decoder *json.Decoder
+	closed  bool
+}
+
+// JSONArrayDecoder starts decoding a request body that is a JSON array.
+//
+//	decoder, err := context.JSONArrayDecoder()
+//	for err == nil && decoder.More() {
+//		widget := &Widget{}
+//		err = decoder.Decode(widget)
+//	}
+func (context *Context) JSONArrayDecoder() (*JSONArrayDecoder, error) {
+	decoder := &JSONArrayDecoder{context: context, decoder: json.NewDecoder(context.BodyReader())}
+	token, err := decoder.decoder.Token()
+	if nil == err && json.Delim('[') != token {
+		err = errors.New("request body is not a JSON array")
+	}
+	if nil != err {
+		return nil, decoder.fail(err)
+	}
+	return decoder, nil
+}
+
+// More checks if there is another element in the array.
+func (decoder *JSONArrayDecoder) More() bool {
+	return !decoder.closed && decoder.decoder.More()
+}
+
+// Decode decodes the next element of the array into the target. It returns
+// io.EOF after the last element.
+func (decoder *JSONArrayDecoder) Decode(target interface{}) error {
+	if decoder.closed {
+		return io.EOF
+	}
+	if !decoder.decoder.More() {
+		decoder.closed = true
+		token, err := decoder.decoder.Token()
+		if nil == err && json.Delim(']') != token {
+			err = errors.New("invalid end of JSON array")
+		}
+		if nil != err {
+			return decoder.fail(err)
+		}
+		return io.EOF
+	}
+	if err := decoder.decoder.Decode(target); nil != err {
+		return decoder.fail(err)
+	}
+	return nil
+}
+
+func (decoder *JSONArrayDecoder) fail(err error) error {
+	decoder.closed = true
+	decoder.context.setBodyError(err)
+	return err
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+// chunkedRequest creates a request with a body of unknown length, as sent
+// with chunked transfer encoding.
+func chunkedRequest(body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.ContentLength = -1
+	return request
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestReadChunked(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, chunkedRequest("chunked body"))
+	body, err := context.Read()
+	assert.NoError(err)
+	assert.Equal("chunked body", string(body))
+	assert.Equal("chunked body", context.Body)
+
+	context = createRequestContext(nil, chunkedRequest(""))
+	body, err = context.Read()
+	assert.Nil(body)
+	assert.IsType(&NoContentError{}, err)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	assert := assert.New(t)
+	controller := &readingController{TestController: NewTestController("Body")}
+	server := CreateRESTServer(":8080", controller)
+	server.MaxBodySize = 8
+
+	recorder := serveRequest(server, newRequest(http.MethodPost, "/", "12345678"))
+	assert.Equal(http.StatusCreated, recorder.Code)
+
+	// rejected from the Content-Length before the controller is called
+	controller.MethodCalled = ""
+	recorder = serveRequest(server, newRequest(http.MethodPost, "/", "123456789"))
+	assert.Equal(http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Contains(recorder.Body.String(), qerror.RequestTooLarge)
+	assert.Equal("", controller.MethodCalled)
+
+	// rejected while reading a chunked body
+	recorder = serveRequest(server, chunkedRequest("123456789"))
+	assert.Equal(http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Equal(http.MethodPost, controller.MethodCalled)
+
+	recorder = serveRequest(server, chunkedRequest("1234"))
+	assert.Equal(http.StatusCreated, recorder.Code)
+}
+
+func TestBodyReader(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	server.MaxBodySize = 4
+	context := createRequestContext(server, chunkedRequest("12345"))
+
+	buffer := make([]byte, 3)
+	n, err := io.ReadFull(context.BodyReader(), buffer)
+	assert.NoError(err)
+	assert.Equal("123", string(buffer[:n]))
+	rest, err := ioutil.ReadAll(context.BodyReader())
+	assert.Equal("4", string(rest))
+	assert.Equal(ErrBodyTooLarge, err)
+
+	assert.Equal(http.NoBody, (&Context{Request: &http.Request{}}).BodyReader())
+}
+
+func TestSetBodyErrorWrapped(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, chunkedRequest("12345"))
+	context.setBodyError(fmt.Errorf("multipart: NextPart: %w", ErrBodyTooLarge))
+	assert.Equal(http.StatusRequestEntityTooLarge, context.Status())
+}
+
+func TestJSONArrayDecoder(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, chunkedRequest(`[{"id":1,"name":"bolt"}, {"id":2,"name":"nut"}]`))
+
+	decoder, err := context.JSONArrayDecoder()
+	assert.NoError(err)
+	widgets := []*encodedWidget{}
+	for decoder.More() {
+		widget := &encodedWidget{}
+		assert.NoError(decoder.Decode(widget))
+		widgets = append(widgets, widget)
+	}
+	assert.Equal([]*encodedWidget{{ID: 1, Name: "bolt"}, {ID: 2, Name: "nut"}}, widgets)
+	assert.Equal(io.EOF, decoder.Decode(&encodedWidget{}))
+	assert.Equal(io.EOF, decoder.Decode(&encodedWidget{}))
+	assert.False(context.HasError())
+}
+
+func TestJSONArrayDecoderErrors(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, chunkedRequest(`{"id":1}`))
+	_, err := context.JSONArrayDecoder()
+	assert.Error(err)
+	assert.Equal(http.StatusNotAcceptable, context.Status())
+
+	context = createRequestContext(nil, chunkedRequest(`[{"id":1}, {"id":`))
+	decoder, err := context.JSONArrayDecoder()
+	assert.NoError(err)
+	assert.NoError(decoder.Decode(&encodedWidget{}))
+	assert.Error(decoder.Decode(&encodedWidget{}))
+	assert.False(decoder.More())
+	assert.Equal(qerror.ValidationError, context.Error.Code)
+
+	server := CreateRESTServer(":8080", nil)
+	server.MaxBodySize = 12
+	context = createRequestContext(server, chunkedRequest(`[{"id":1}, {"id":2}]`))
+	decoder, err = context.JSONArrayDecoder()
+	assert.NoError(err)
+	assert.NoError(decoder.Decode(&encodedWidget{}))
+	assert.Equal(ErrBodyTooLarge, decoder.Decode(&encodedWidget{}))
+	assert.Equal(http.StatusRequestEntityTooLarge, context.Status())
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Kasita-Inc/gadget/stringutil"
+)
+
+// ParameterConstraint checks if a URI segment is a valid value for a
+// constrained route template parameter such as '{{id:int}}'.
+type ParameterConstraint func(segment string) bool
+
+const (
+	parameterClose     = "}}"
+	constraintOperator = ":"
+	catchAllSuffix     = "..."
+	regexConstraint    = "regex"
+)
+
+var parameterConstraints = map[string]ParameterConstraint{
+	"int":  isInt,
+	"uuid": isUUID,
+}
+
+// RegisterParameterConstraint makes a named constraint available to route
+// templates, e.g. registering 'hex' allows routes such as 'colors/{{id:hex}}'.
+// Constraints should be registered before routes using them are added.
+func RegisterParameterConstraint(name string, constraint ParameterConstraint) {
+	parameterConstraints[name] = constraint
+}
+
+func isInt(segment string) bool {
+	_, err := strconv.ParseInt(segment, 10, 64)
+	return err == nil
+}
+
+func isUUID(segment string) bool {
+	if len(segment) != 36 {
+		return false
+	}
+	for i, c := range segment {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// routeParameter is a parsed route template parameter segment.
+type routeParameter struct {
+	name       string
+	constraint string
+	// catchAll parameters such as '{{path...}}' match the remainder of the path
+	catchAll bool
+}
+
+// parseParameter parses a route template segment such as '{{id:int}}' or
+// '{{path...}}'.
+func parseParameter(segment string) (routeParameter, error) {
+	parameter := routeParameter{}
+	if !strings.HasPrefix(segment, stringutil.DOpen) || !strings.HasSuffix(segment, parameterClose) ||
+		len(segment) < len(stringutil.DOpen)+len(parameterClose) {
+		return parameter, fmt.Errorf("invalid route parameter '%s'", segment)
+	}
+	inner := segment[len(stringutil.DOpen) : len(segment)-len(parameterClose)]
+	parameter.name = inner
+	if i := strings.Index(inner, constraintOperator); i >= 0 {
+		parameter.name, parameter.constraint = inner[:i], inner[i+1:]
+	}
+	if strings.HasSuffix(parameter.name, catchAllSuffix) {
+		parameter.name = strings.TrimSuffix(parameter.name, catchAllSuffix)
+		parameter.catchAll = true
+	}
+	if stringutil.IsWhiteSpace(parameter.name) || strings.ContainsAny(parameter.name, "{}") {
+		return parameter, fmt.Errorf("invalid route parameter '%s'", segment)
+	}
+	if parameter.catchAll && "" != parameter.constraint {
+		return parameter, fmt.Errorf("catch-all route parameter '%s' cannot be constrained", segment)
+	}
+	return parameter, nil
+}
+
+// compileConstraint returns the ParameterConstraint for the passed
+// constraint such as 'int' or 'regex([a-z-]+)'.
+func compileConstraint(constraint string) (ParameterConstraint, error) {
+	if strings.HasPrefix(constraint, regexConstraint+"(") && strings.HasSuffix(constraint, ")") {
+		expression := constraint[len(regexConstraint)+1 : len(constraint)-1]
+		re, err := regexp.Compile("^(?:" + expression + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid route parameter regex '%s': %s", expression, err)
+		}
+		return re.MatchString, nil
+	}
+	match, ok := parameterConstraints[constraint]
+	if !ok {
+		return nil, fmt.Errorf("unknown route parameter constraint '%s'", constraint)
+	}
+	return match, nil
+}
@@ -1,14 +1,16 @@
 package http
 
 import (
-	"encoding/json"
+	stdcontext "context"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"net/url"
-	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Kasita-Inc/gadget/errors"
 	"github.com/Kasita-Inc/gadget/log"
@@ -44,13 +46,17 @@ func (err *NoContentError) Trace() []string {
 }
 
 // Context serves as a structure that tracks the state of a given http Request
-// Response chain.
+// Response chain. Context implements context.Context, it is cancelled when
+// the request times out or the client disconnects.
 type Context struct {
 	URIParameters map[string]string
 	URLParameters url.Values
 	URI           string
 	URL           *url.URL
 	Method        string
+	// RequestID correlates the request across access logs, errors, and
+	// upstream calls.
+	RequestID string
 
 	Request  *http.Request
 	Response http.ResponseWriter
@@ -64,13 +70,91 @@ type Context struct {
 
 	Body     string
 	bodyRead bool
+
+	formFiles map[string][]*FormFile
+	uploads   []string
+
+	server *RESTServer
+	ctx    stdcontext.Context
+	start  time.Time
+	span   *Span
 }
 
 // Status returns the HTTP status of the response
 func (context *Context) Status() int {
+	if writer, ok := context.Response.(*ResponseWriter); ok && writer.Written() {
+		return writer.Status()
+	}
 	return context.responseStatus
 }
 
+// responseWriter returns the ResponseWriter tracking the response, wrapping
+// the Response if it has been replaced.
+func (context *Context) responseWriter() *ResponseWriter {
+	if writer, ok := context.Response.(*ResponseWriter); ok {
+		return writer
+	}
+	writer := newResponseWriter(context.Response, context.Request)
+	context.Response = writer
+	return writer
+}
+
+func (context *Context) requestContext() stdcontext.Context {
+	if nil != context.ctx {
+		return context.ctx
+	}
+	if nil != context.Request {
+		return context.Request.Context()
+	}
+	return stdcontext.Background()
+}
+
+// Deadline returns the time the request times out, see context.Context.
+func (context *Context) Deadline() (time.Time, bool) {
+	return context.requestContext().Deadline()
+}
+
+// Done returns a channel that is closed when the request times out or the
+// client disconnects, see context.Context. The timeout response is not sent
+// until the controller returns, so controllers should return once it is
+// closed.
+func (context *Context) Done() <-chan struct{} {
+	return context.requestContext().Done()
+}
+
+// Err returns why Done was closed, see context.Context.
+func (context *Context) Err() error {
+	return context.requestContext().Err()
+}
+
+// Value returns the value associated with the key on the request
+// context.Context.
+func (context *Context) Value(key interface{}) interface{} {
+	return context.requestContext().Value(key)
+}
+
+// WithValue associates the value with the key on the request context.Context.
+func (context *Context) WithValue(key, value interface{}) {
+	context.ctx = stdcontext.WithValue(context.requestContext(), key, value)
+}
+
+// Span returns the span tracing the request, child spans should be started
+// from it. It returns nil if the server has no Tracer, which is safe to use.
+func (context *Context) Span() *Span {
+	return context.span
+}
+
+// Disconnected returns true if the client closed the connection before the
+// request completed.
+func (context *Context) Disconnected() bool {
+	return nil != context.Request && stdcontext.Canceled == context.Request.Context().Err()
+}
+
+// Draining returns true if the server handling the request is shutting down.
+func (context *Context) Draining() bool {
+	return nil != context.server && context.server.Draining()
+}
+
 // SetError sets the HTTP status of the response and the error to be returned
 func (context *Context) SetError(err *qerror.RestError, status int) {
 	context.responseStatus = status
@@ -91,6 +175,29 @@ func (context *Context) AddError(err qerror.FieldError) {
 	context.Error.AddDetail(err)
 }
 
+// Fail sets an error with the code on the Context, returned with the status
+// registered for the code, see qerror.Register. The message template of the
+// code is localized for the Accept-Language header and formatted with the
+// passed args.
+func (context *Context) Fail(code string, args ...interface{}) {
+	definition, ok := qerror.Lookup(code)
+	if !ok {
+		log.Errorf("%s %s: failed with unregistered error code '%s'", context.Method, context.URI, code)
+		definition.Status = http.StatusInternalServerError
+	}
+	message := qerror.Format(qerror.Localize(code, context.languages()...), args...)
+	context.SetError(qerror.NewRestError(code, message, nil), definition.Status)
+}
+
+// languages returns the languages of the Accept-Language header of the
+// request in order of preference.
+func (context *Context) languages() []string {
+	if nil == context.Request {
+		return nil
+	}
+	return acceptedLanguages(context.Request.Header.Get(acceptLanguageHeader))
+}
+
 // SetResponse sets the HTTP status and model to be rendered in the response write
 // Returns false if there is an Error on the context otherwise true
 func (context *Context) SetResponse(model interface{}, status int) bool {
@@ -104,12 +211,13 @@ func (context *Context) SetResponse(model interface{}, status int) bool {
 
 // CreateContext initializes a Context from the passed Response and Request
 // pair, and router. The router is used for detemplating and populating the
-// URIParameters
+// URIParameters. The request is authenticated by the RESTServer when it is
+// handled.
 func CreateContext(writer http.ResponseWriter, request *http.Request,
 	router Router) *Context {
 	var err error
-	context := &Context{Request: request, Extended: make(map[string]interface{})}
-	context.Response = writer
+	context := &Context{Request: request, Extended: make(map[string]interface{}), start: time.Now()}
+	context.Response = newResponseWriter(writer, request)
 	context.URL = request.URL
 	context.URI = request.RequestURI
 	context.Method = request.Method
@@ -117,151 +225,137 @@ func CreateContext(writer http.ResponseWriter, request *http.Request,
 
 	if err != nil {
 		// take a hard stance on malformed URL's
-		context.SetError(qerror.NewRestError(qerror.MalformedURL, fmt.Sprintf("Malformed URL Parameters '%s'.", request.URL), nil),
-			http.StatusBadRequest)
+		context.Fail(qerror.MalformedURL, request.URL)
 		return context
 	}
 
-	cleanPath := strings.Split(context.URI, "?")[0]
-	cleanPath = strings.Trim(cleanPath, " /")
+	cleanPath := cleanPath(context.URI)
 	context.Route, err = router.FindRouteForPath(cleanPath)
 	if err != nil || context.Route == nil {
-		context.SetError(qerror.NewRestError(qerror.InvalidRoute, "", nil), http.StatusBadRequest)
+		context.Fail(qerror.NotFound)
 		return context
 	}
 
 	context.URIParameters = make(map[string]string)
 	if !stringutil.IsWhiteSpace(cleanPath) {
-		context.URIParameters, err = stringutil.Detemplate(context.Route.TemplateRoute, cleanPath)
+		context.URIParameters, err = detemplate(context.Route.TemplateRoute, cleanPath)
 		if err != nil {
-			context.SetError(qerror.NewRestError(qerror.InvalidRoute, "", nil), http.StatusInternalServerError)
+			context.Fail(qerror.InvalidRoute)
 			return context
 		}
 	}
 
-	if http.MethodOptions != context.Request.Method && !context.Route.Controller.Authenticate(context) {
-		context.SetError(
-			qerror.NewRestError(qerror.AuthenticationFailed, InvalidCredentialsErrorMessage, nil), http.StatusUnauthorized)
+	return context
+}
+
+// URIParameterInt returns the named URI parameter parsed as an int.
+func (context *Context) URIParameterInt(name string) (int, error) {
+	value, ok := context.URIParameters[name]
+	if !ok {
+		return 0, errors.New("URI parameter '%s' not present", name)
 	}
+	return strconv.Atoi(value)
+}
 
-	return context
+// URIParameterUUID returns the named URI parameter as a lower case UUID
+// string, returning an error if the parameter is not a UUID.
+func (context *Context) URIParameterUUID(name string) (string, error) {
+	value, ok := context.URIParameters[name]
+	if !ok {
+		return "", errors.New("URI parameter '%s' not present", name)
+	}
+	if !isUUID(value) {
+		return "", errors.New("URI parameter '%s' is not a UUID (%s)", name, value)
+	}
+	return strings.ToLower(value), nil
+}
+
+// cleanPath strips the query string and any leading or trailing slashes
+// from the passed request URI.
+func cleanPath(uri string) string {
+	return strings.Trim(strings.Split(uri, "?")[0], " /")
 }
 
 // InvalidCredentialsErrorMessage is returned when Credentials are invalid
 const InvalidCredentialsErrorMessage = "Invalid Credentials"
 
 // Read reads the entire body of the request and returns it as a slice of
-// bytes
+// bytes. Bodies of unknown length, such as chunked bodies, are read until
+// the end. A body larger than the MaxBodySize of the server sets a
+// request-too-large error.
 func (context *Context) Read() ([]byte, error) {
 	if context.bodyRead {
 		return []byte(context.Body), nil
 	}
-	if context.Request.ContentLength <= 0 {
+	if 0 == context.Request.ContentLength || nil == context.Request.Body {
 		return nil, NewNoContentError("", "")
 	}
-	body := make([]byte, context.Request.ContentLength)
-	n, err := io.ReadFull(context.Request.Body, body)
-
-	if err == io.ErrUnexpectedEOF {
-		log.Errorf("warning:%s:%s: Request.ContentLength (%d) mismatch with actual body length (%d)", context.URI,
-			context.Request.RemoteAddr, n, context.Request.ContentLength)
+	var body []byte
+	var err error
+	if context.Request.ContentLength < 0 {
+		if body, err = context.readAll(); nil == body && !stderrors.Is(err, ErrBodyTooLarge) {
+			return nil, err
+		}
+	} else {
+		body = make([]byte, context.Request.ContentLength)
+		var n int
+		n, err = io.ReadFull(context.Request.Body, body)
+
+		if err == io.ErrUnexpectedEOF {
+			log.Errorf("warning:%s:%s: Request.ContentLength (%d) mismatch with actual body length (%d)", context.URI,
+				context.Request.RemoteAddr, n, context.Request.ContentLength)
+		}
+		// Ignore EOF error
+		if io.EOF == err {
+			err = nil
+		}
 	}
-	// Ignore EOF error
-	if io.EOF == err {
-		err = nil
+	if stderrors.Is(err, ErrBodyTooLarge) {
+		context.bodyTooLarge()
+		return nil, err
 	}
 	context.Body = string(body)
 	context.bodyRead = true
 	return body, err
 }
 
-// readJSON takes the JSON content type body of the Request and unmarshals a JSON object the
-// same type as the passed implementation of interface{}
-func (context *Context) readJSON(body []byte, target interface{}) error {
-	return json.Unmarshal(body, target)
-}
-
-// readForm takes the form-urlencoded content type body of the Request and populates an object
-// of the same type as the passed interface{}
-func (context *Context) readForm(body []byte, target interface{}) error {
-	values, err := url.ParseQuery(string(body))
-	if nil != err {
+// ReadObject reads the body of the Request and unmarshals an object the
+// same type as the passed implementation of interface{}, using the decoder
+// registered for the request Content-Type, see RegisterDecoder. A missing
+// Content-Type, or one without a decoder, fails with unsupported-media-type.
+// multipart/form-data bodies are read by ReadMultipart. The object is then
+// checked against its 'validate' tags, see Validate.
+func (context *Context) ReadObject(target interface{}) error {
+	if err := context.readObject(target); nil != err {
 		return err
 	}
-	return context.valuesToObject(values, target)
+	return context.validate(target)
 }
 
-func (context *Context) inspectModel(target interface{}) map[string]interface{} {
-	v := reflect.Indirect(reflect.ValueOf(target))
-	fieldMap := make(map[string]interface{}, v.NumField())
-	for i := 0; i < v.NumField(); i++ {
-		fieldMap[v.Type().Field(i).Name] = v.Field(i).Type()
-	}
-	return fieldMap
-}
-
-func (context *Context) valuesToObject(values url.Values, target interface{}) error {
-	fieldMap := context.inspectModel(target)
-	valueMap := make(map[string]interface{})
-	for fieldName, fieldType := range fieldMap {
-		switch fieldType.(reflect.Type).Kind() {
-		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Struct, reflect.UnsafePointer:
-			break
-		case reflect.Slice, reflect.Array:
-			var arrayValues []string
-			arrayFieldName := stringutil.Underscore(fieldName)
-			queryVal := values.Get(arrayFieldName)
-			// ignore empty query string arguments
-			if !stringutil.IsEmpty(queryVal) {
-				arrayValues = append(arrayValues, values[arrayFieldName]...)
-			}
-			arrayFieldName = stringutil.Underscore(fieldName) + "[]"
-			queryVal = values.Get(arrayFieldName)
-			if !stringutil.IsEmpty(queryVal) {
-				arrayValues = append(arrayValues, values[arrayFieldName]...)
-			}
-			if len(arrayValues) > 0 {
-				valueMap[fieldName] = arrayValues
-			}
-		default:
-			queryVal := values.Get(stringutil.Underscore(fieldName))
-			if !stringutil.IsEmpty(queryVal) {
-				valueMap[fieldName] = queryVal
-			}
-		}
+func (context *Context) readObject(target interface{}) error {
+	if mediaType, _, _ := mime.ParseMediaType(context.Request.Header.Get(contentTypeHeader)); contentTypeMultipart == mediaType {
+		return context.readMultipart(target)
 	}
-	data, err := json.Marshal(valueMap)
-	if nil != err {
-		return err
-	}
-	return json.Unmarshal(data, target)
-}
-
-// ReadObject reads the body of the Request and unmarshals an object the
-// same type as the passed implementation of interface{}
-func (context *Context) ReadObject(target interface{}) error {
 	body, err := context.Read()
 
 	if err != nil {
 		return err
 	}
 
-	contentType, _, err := mime.ParseMediaType(context.Request.Header.Get(contentTypeHeader))
+	header := context.Request.Header.Get(contentTypeHeader)
+	contentType, _, err := mime.ParseMediaType(header)
 	if nil != err {
-		context.SetError(qerror.NewRestError(qerror.ValidationError, err.Error(), nil), http.StatusNotAcceptable)
+		context.Fail(qerror.UnsupportedMediaType, header)
 		return err
 	}
-	switch contentType {
-	case contentTypeForm:
-		err = context.readForm(body, target)
-	case contentTypeJSON:
-		err = context.readJSON(body, target)
-	default:
-		err = errors.New("Unsupported contentType (%s) provided", contentType)
+	decoder, ok := decoderFor(contentType)
+	if !ok {
+		context.Fail(qerror.UnsupportedMediaType, contentType)
+		return errors.New("Unsupported contentType (%s) provided", contentType)
 	}
 
-	if nil != err {
-		context.SetError(qerror.NewRestError(qerror.ValidationError, err.Error(), nil), http.StatusNotAcceptable)
+	if err = decoder(body, target); nil != err {
+		context.setBindError(err)
 	}
 
 	return err
@@ -272,7 +366,28 @@ func (context *Context) Write(s string) {
 	context.Response.Write([]byte(s))
 }
 
-// ReadQueryParams converts URL Parameters into an Object
+// ReadQueryParams binds the URL Parameters to the fields of the target named
+// by their 'query' tag, or the underscored field name, and validates it.
+// Values that cannot be converted to the type of their field are reported as
+// FieldErrors.
 func (context *Context) ReadQueryParams(target interface{}) error {
-	return context.valuesToObject(context.URLParameters, target)
+	if err := bindValues(context.URLParameters, target, queryTag, context.languages()...); nil != err {
+		context.setBindError(err)
+		return err
+	}
+	return context.validate(target)
+}
+
+// setBindError sets the error for a request that could not be bound to a
+// target, adding a FieldError for each field that could not be converted.
+// Other errors, such as a body that cannot be decoded, are returned as the
+// message of a validation-error with a 406 status.
+func (context *Context) setBindError(err error) {
+	if fieldErrors, ok := err.(FieldErrors); ok {
+		for _, fieldError := range fieldErrors {
+			context.AddError(fieldError)
+		}
+		return
+	}
+	context.SetError(qerror.NewRestError(qerror.ValidationError, err.Error(), nil), http.StatusNotAcceptable)
 }
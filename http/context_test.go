@@ -1,9 +1,11 @@
 package http
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -119,8 +121,12 @@ func TestCreateContextWithFailingAuth(t *testing.T) {
 	router := CreateRouter(&c)
 	context := CreateContext(w, &r, router)
 
-	assert.True(context.HasError())
-	assert.Equal(qerror.AuthenticationFailed, context.Error.Code)
+	// the request is authenticated when it is handled by the server
+	assert.False(context.HasError())
+	server := CreateRESTServer(":8080", &c)
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusUnauthorized, recorder.Code)
+	assert.Empty(c.MethodCalled)
 }
 
 func TestCreateContextBadParameters(t *testing.T) {
@@ -157,8 +163,8 @@ func TestCreateContextBadRoute(t *testing.T) {
 	context := CreateContext(w, &r, router)
 
 	assert.True(context.HasError())
-	assert.Equal(qerror.InvalidRoute, context.Error.Code)
-	assert.Equal(http.StatusBadRequest, context.Status())
+	assert.Equal(qerror.NotFound, context.Error.Code)
+	assert.Equal(http.StatusNotFound, context.Status())
 }
 
 func TestCreateContextBadTemplate(t *testing.T) {
@@ -312,6 +318,14 @@ func TestReadObject_withJSON_BadInput(t *testing.T) {
 
 	assert.Error(err)
 	assert.True(context.HasError())
+	// the body has no Content-Type
+	assert.Equal(http.StatusUnsupportedMediaType, context.Status())
+
+	r.Header = http.Header{contentTypeHeader: {contentTypeJSON}}
+	context = Context{
+		Request: &r,
+	}
+	assert.Error(context.ReadObject(body))
 	assert.Equal(http.StatusNotAcceptable, context.Status())
 }
 
@@ -472,3 +486,118 @@ func TestWrite(t *testing.T) {
 	context.Write("foo")
 	assert.Equal("foo", stringutil.NullTerminatedString(writerBody))
 }
+
+func TestURIParameterInt(t *testing.T) {
+	assert := assert.New(t)
+	context := Context{URIParameters: map[string]string{"id": "42", "name": "foo"}}
+
+	id, err := context.URIParameterInt("id")
+	assert.NoError(err)
+	assert.Equal(42, id)
+
+	_, err = context.URIParameterInt("name")
+	assert.Error(err)
+	_, err = context.URIParameterInt("missing")
+	assert.Error(err)
+}
+
+func TestURIParameterUUID(t *testing.T) {
+	assert := assert.New(t)
+	context := Context{URIParameters: map[string]string{
+		"id":   "6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"name": "foo",
+	}}
+
+	id, err := context.URIParameterUUID("id")
+	assert.NoError(err)
+	assert.Equal("6ba7b810-9dad-11d1-80b4-00c04fd430c8", id)
+
+	_, err = context.URIParameterUUID("name")
+	assert.Error(err)
+	_, err = context.URIParameterUUID("missing")
+	assert.Error(err)
+}
+
+func TestCreateContextConstrainedParameters(t *testing.T) {
+	assert := assert.New(t)
+
+	u, _ := url.Parse("http://127.0.0.1/orders/42")
+	r := http.Request{
+		URL:        u,
+		RequestURI: u.RequestURI(),
+	}
+	c := NewTestController("HTTP Test")
+	c.Routes = append(c.Routes, "orders/{{id:int}}")
+	router := CreateRouter(&c)
+	assert.NoError(router.AddController(&c))
+
+	context := CreateContext(testResponseWriter{}, &r, router)
+	assert.False(context.HasError())
+	id, err := context.URIParameterInt("id")
+	assert.NoError(err)
+	assert.Equal(42, id)
+}
+
+func TestCreateContextCatchAll(t *testing.T) {
+	assert := assert.New(t)
+
+	u, _ := url.Parse("http://127.0.0.1/files/docs/guide/intro.md?download=true")
+	r := http.Request{
+		URL:        u,
+		RequestURI: u.RequestURI(),
+	}
+	c := NewTestController("HTTP Test")
+	c.Routes = append(c.Routes, "files/{{path...}}")
+	router := CreateRouter(&c)
+	assert.NoError(router.AddController(&c))
+
+	context := CreateContext(testResponseWriter{}, &r, router)
+	assert.False(context.HasError())
+	assert.Equal("docs/guide/intro.md", context.URIParameters["path"])
+}
+
+func TestContextImplementsContext(t *testing.T) {
+	assert := assert.New(t)
+	var ctx stdcontext.Context = &Context{}
+	_, ok := ctx.Deadline()
+	assert.False(ok)
+	assert.Nil(ctx.Err())
+
+	type key string
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(stdcontext.WithValue(r.Context(), key("request"), "value"))
+	context := &Context{Request: r}
+	assert.Equal("value", context.Value(key("request")))
+	context.WithValue(key("added"), "other")
+	assert.Equal("other", context.Value(key("added")))
+	assert.Equal("value", context.Value(key("request")))
+	assert.False(context.Disconnected())
+}
+
+func TestFail(t *testing.T) {
+	assert := assert.New(t)
+	qerror.RegisterCatalog(qerror.MapCatalog{
+		"es": {qerror.RequestTooLarge: "El cuerpo supera el tamaño máximo de %d bytes."},
+	})
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	context := &Context{Request: request}
+
+	context.Fail(qerror.RequestTooLarge, 10)
+	assert.Equal(http.StatusRequestEntityTooLarge, context.Status())
+	assert.Equal(qerror.RequestTooLarge, context.Error.Code)
+	assert.Equal("Request body exceeds the maximum size of 10 bytes.", context.Error.Message)
+
+	request.Header.Set(acceptLanguageHeader, "en;q=0.5, es-MX, *")
+	context.Fail(qerror.RequestTooLarge, 10)
+	assert.Equal("El cuerpo supera el tamaño máximo de 10 bytes.", context.Error.Message)
+
+	context.Fail("unregistered")
+	assert.Equal(http.StatusInternalServerError, context.Status())
+	assert.Equal("unregistered", context.Error.Code)
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"fr-ca", "fr", "en"}, acceptedLanguages("en;q=0.5, fr-CA, fr;q=0.8, de;q=0, *;q=0.1"))
+	assert.Empty(acceptedLanguages(""))
+}
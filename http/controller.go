@@ -1,5 +1,11 @@
 package http
 
+import (
+	"net/http"
+	"reflect"
+	"sync"
+)
+
 // Controller is the main interface for the request handlers in the Router
 type Controller interface {
 	GetRoutes() []string
@@ -12,5 +18,85 @@ type Controller interface {
 	Authenticate(context *Context) bool
 }
 
+// AllowedMethodsController can be implemented by a Controller to declare the
+// HTTP methods it implements. Requests for other methods are rejected with a
+// 405 without calling the Controller, and the methods are reported in the
+// Allow header.
+type AllowedMethodsController interface {
+	AllowedMethods() []string
+}
+
+// controllerMethods are the HTTP methods dispatched to a Controller.
+var controllerMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// rejectedMethods are the methods each type of Controller not implementing
+// AllowedMethodsController has rejected with a method-not-allowed error,
+// keyed by the reflect.Type of the Controller.
+var rejectedMethods sync.Map
+
+// rejectMethod records that the Controller rejected the method, a rejected
+// HEAD request is recorded as GET.
+func rejectMethod(controller Controller, method string) {
+	if _, ok := controller.(AllowedMethodsController); ok {
+		return
+	}
+	if http.MethodHead == method {
+		method = http.MethodGet
+	}
+	rejected := []string{}
+	if methods, ok := rejectedMethods.Load(reflect.TypeOf(controller)); ok {
+		rejected = methods.([]string)
+	}
+	if !containsMethod(rejected, method) {
+		rejectedMethods.Store(reflect.TypeOf(controller), append(append([]string{}, rejected...), method))
+	}
+}
+
+// AllowedMethods returns the HTTP methods supported by the passed Controller.
+// Controllers that do not implement AllowedMethodsController are assumed to
+// support every method on the Controller interface, other than the methods
+// they have rejected with a method-not-allowed error. HEAD is supported by
+// any Controller supporting GET and OPTIONS is always supported.
+func AllowedMethods(controller Controller) []string {
+	rejected := []string{}
+	if methods, ok := rejectedMethods.Load(reflect.TypeOf(controller)); ok {
+		rejected = methods.([]string)
+	}
+	return allowedMethods(controller, rejected)
+}
+
+// allowedMethods returns the HTTP methods supported by the passed Controller
+// other than the rejected methods.
+func allowedMethods(controller Controller, rejected []string) []string {
+	declared := controllerMethods
+	if c, ok := controller.(AllowedMethodsController); ok {
+		declared = c.AllowedMethods()
+	}
+	methods := []string{}
+	for _, method := range declared {
+		if containsMethod(rejected, method) {
+			continue
+		}
+		if http.MethodHead != method && http.MethodOptions != method {
+			methods = append(methods, method)
+		}
+		if http.MethodGet == method {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	return append(methods, http.MethodOptions)
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // HealthCheckRoute is the default URI for quimby health checks
 const HealthCheckRoute = "health"
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	originHeader                        = "Origin"
+	varyHeader                          = "Vary"
+	accessControlAllowOriginHeader      = "Access-Control-Allow-Origin"
+	accessControlAllowCredentialsHeader = "Access-Control-Allow-Credentials"
+	accessControlAllowMethodsHeader     = "Access-Control-Allow-Methods"
+	accessControlAllowHeadersHeader     = "Access-Control-Allow-Headers"
+	accessControlExposeHeadersHeader    = "Access-Control-Expose-Headers"
+	accessControlMaxAgeHeader           = "Access-Control-Max-Age"
+	accessControlRequestMethodHeader    = "Access-Control-Request-Method"
+	accessControlRequestHeadersHeader   = "Access-Control-Request-Headers"
+	corsAny                             = "*"
+)
+
+// CORSPolicy configures the Cross-Origin Resource Sharing headers returned to
+// browser clients, see RESTServer.UseCORS.
+type CORSPolicy struct {
+	// AllowedOrigins that may access the resources, '*' allows any origin and
+	// patterns such as 'https://*.example.com' match any subdomain.
+	AllowedOrigins []string
+	// AllowedMethods that may be used, defaults to the methods allowed for
+	// the route.
+	AllowedMethods []string
+	// AllowedHeaders that may be sent on requests, '*' allows any header.
+	AllowedHeaders []string
+	// ExposedHeaders that browsers allow clients to read from responses.
+	ExposedHeaders []string
+	// AllowCredentials permits cookies and authorization headers on requests.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the result of a preflight request.
+	MaxAge time.Duration
+}
+
+type prefixCORSPolicy struct {
+	prefix []string
+	policy *CORSPolicy
+}
+
+// UseCORS applies the CORS policy to requests whose path begins with the
+// passed route prefix, an empty prefix applies the policy to every request.
+// When several prefixes match a request the longest is used. Policies that
+// allow any origin with credentials are rejected, list the origins instead.
+func (server *RESTServer) UseCORS(prefix string, policy *CORSPolicy) error {
+	if containsFold(policy.AllowedOrigins, corsAny) && policy.AllowCredentials {
+		return fmt.Errorf("CORS policy for '%s' cannot allow credentials from any origin", prefix)
+	}
+	segments := []string{}
+	if "" != strings.TrimSpace(prefix) {
+		var err error
+		if segments, err = splitPrefix(prefix); err != nil {
+			return err
+		}
+	}
+	server.corsPolicies = append(server.corsPolicies, prefixCORSPolicy{prefix: segments, policy: policy})
+	return nil
+}
+
+// corsPolicyFor returns the policy registered for the longest prefix of the
+// request path, or nil if there is none.
+func (server *RESTServer) corsPolicyFor(context *Context) *CORSPolicy {
+	var found *prefixCORSPolicy
+	path := strings.Split(cleanPath(context.URI), Slash)
+	for i, registered := range server.corsPolicies {
+		if hasPrefix(path, registered.prefix) && (nil == found || len(registered.prefix) > len(found.prefix)) {
+			found = &server.corsPolicies[i]
+		}
+	}
+	if nil == found {
+		return nil
+	}
+	return found.policy
+}
+
+// isPreflight checks if the passed request is a CORS preflight request.
+func isPreflight(request *http.Request) bool {
+	return http.MethodOptions == request.Method && "" != request.Header.Get(originHeader) &&
+		"" != request.Header.Get(accessControlRequestMethodHeader)
+}
+
+// matchPattern checks if the value matches the passed pattern, where '*'
+// matches any sequence of characters.
+func matchPattern(pattern, value string) bool {
+	parts := strings.Split(strings.ToLower(pattern), corsAny)
+	value = strings.ToLower(value)
+	if 1 == len(parts) {
+		return parts[0] == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsOrigin checks if the passed origin matches the AllowedOrigins.
+func (policy *CORSPolicy) allowsOrigin(origin string) bool {
+	for _, pattern := range policy.AllowedOrigins {
+		if matchPattern(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// decorate adds the CORS headers for the request origin to the response. It
+// returns false if the request has no origin or the origin is not allowed.
+// Unless any origin is allowed the response varies by origin, even when the
+// origin is not allowed, so caches do not share it between origins.
+func (policy *CORSPolicy) decorate(context *Context) bool {
+	header := context.Response.Header()
+	anyOrigin := containsFold(policy.AllowedOrigins, corsAny)
+	if !anyOrigin {
+		header.Add(varyHeader, originHeader)
+	}
+	origin := context.Request.Header.Get(originHeader)
+	if "" == origin || !policy.allowsOrigin(origin) {
+		return false
+	}
+	if anyOrigin {
+		header.Set(accessControlAllowOriginHeader, corsAny)
+	} else {
+		header.Set(accessControlAllowOriginHeader, origin)
+	}
+	if policy.AllowCredentials {
+		header.Set(accessControlAllowCredentialsHeader, "true")
+	}
+	if !isPreflight(context.Request) && len(policy.ExposedHeaders) > 0 {
+		header.Set(accessControlExposeHeadersHeader, strings.Join(policy.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+// preflight answers a CORS preflight request for a route supporting the
+// passed methods. It returns false if the requested method or headers are
+// not allowed, leaving the response to be completed as a plain OPTIONS.
+func (policy *CORSPolicy) preflight(context *Context, routeMethods []string) bool {
+	methods := policy.AllowedMethods
+	if 0 == len(methods) {
+		methods = routeMethods
+	}
+	if !containsMethod(methods, context.Request.Header.Get(accessControlRequestMethodHeader)) {
+		return false
+	}
+	requested := []string{}
+	for _, h := range strings.Split(context.Request.Header.Get(accessControlRequestHeadersHeader), ",") {
+		if h = strings.TrimSpace(h); "" != h {
+			requested = append(requested, h)
+		}
+	}
+	allowedHeaders := policy.AllowedHeaders
+	if containsFold(allowedHeaders, corsAny) {
+		allowedHeaders = requested
+	}
+	for _, h := range requested {
+		if !containsFold(allowedHeaders, h) {
+			return false
+		}
+	}
+
+	header := context.Response.Header()
+	header.Add(varyHeader, accessControlRequestMethodHeader)
+	header.Add(varyHeader, accessControlRequestHeadersHeader)
+	header.Set(accessControlAllowMethodsHeader, strings.Join(methods, ", "))
+	if len(allowedHeaders) > 0 {
+		header.Set(accessControlAllowHeadersHeader, strings.Join(allowedHeaders, ", "))
+	}
+	if policy.MaxAge > 0 {
+		header.Set(accessControlMaxAgeHeader, strconv.Itoa(int(policy.MaxAge/time.Second)))
+	}
+	context.SetResponse(nil, http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+func createCORSServer() (*RESTServer, *declaredMethodsController) {
+	controller := &declaredMethodsController{TestController: NewTestController("CORS")}
+	server := CreateRESTServer(":8080", nil)
+	server.Router.AddRoute("api/widgets", controller)
+	server.Router.AddRoute("public/widgets", controller)
+	return server, controller
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestMatchPattern(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(matchPattern("*", "https://example.com"))
+	assert.True(matchPattern("https://example.com", "HTTPS://Example.com"))
+	assert.True(matchPattern("https://*.example.com", "https://api.example.com"))
+	assert.True(matchPattern("https://*.example.com:*", "https://api.example.com:8443"))
+	assert.False(matchPattern("https://*.example.com", "https://example.com"))
+	assert.False(matchPattern("https://*.example.com", "https://api.example.org"))
+	assert.False(matchPattern("https://example.com", "https://example.com.evil.org"))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	assert := assert.New(t)
+	server, controller := createCORSServer()
+	assert.NoError(server.UseCORS("", &CORSPolicy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+
+	recorder := serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://app.example.com",
+		accessControlRequestMethodHeader, http.MethodPut, accessControlRequestHeadersHeader, "content-type, authorization")
+	assert.Equal(http.StatusNoContent, recorder.Code)
+	assert.Equal("", controller.MethodCalled)
+	assert.Equal("https://app.example.com", recorder.Header().Get(accessControlAllowOriginHeader))
+	assert.Equal("true", recorder.Header().Get(accessControlAllowCredentialsHeader))
+	assert.Equal("GET, HEAD, PUT, OPTIONS", recorder.Header().Get(accessControlAllowMethodsHeader))
+	assert.Equal("Authorization, Content-Type", recorder.Header().Get(accessControlAllowHeadersHeader))
+	assert.Equal("600", recorder.Header().Get(accessControlMaxAgeHeader))
+	assert.Contains(recorder.Header()[varyHeader], originHeader)
+}
+
+func TestCORSPreflightBeforeMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := createCORSServer()
+	assert.NoError(server.UseCORS("", &CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}))
+	server.Use(func(context *Context, next Handler) {
+		if "" == context.Request.Header.Get("Authorization") {
+			context.Fail(qerror.AuthenticationFailed)
+			return
+		}
+		next(context)
+	})
+
+	recorder := serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://app.example.com", accessControlRequestMethodHeader, http.MethodGet)
+	assert.Equal(http.StatusNoContent, recorder.Code)
+	assert.Equal("https://app.example.com", recorder.Header().Get(accessControlAllowOriginHeader))
+
+	// the middleware still applies to requests that are not allowed preflights
+	recorder = serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://evil.org", accessControlRequestMethodHeader, http.MethodGet)
+	assert.Equal(http.StatusUnauthorized, recorder.Code)
+	recorder = serve(server, http.MethodGet, "/api/widgets", originHeader, "https://app.example.com")
+	assert.Equal(http.StatusUnauthorized, recorder.Code)
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := createCORSServer()
+	assert.NoError(server.UseCORS("", &CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+	}))
+
+	// method not supported by the route
+	recorder := serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://app.example.com", accessControlRequestMethodHeader, http.MethodDelete)
+	assert.Empty(recorder.Header().Get(accessControlAllowMethodsHeader))
+
+	// header not allowed
+	recorder = serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://app.example.com",
+		accessControlRequestMethodHeader, http.MethodGet, accessControlRequestHeadersHeader, "X-Secret")
+	assert.Empty(recorder.Header().Get(accessControlAllowMethodsHeader))
+
+	// origin not allowed
+	recorder = serve(server, http.MethodOptions, "/api/widgets", originHeader, "https://evil.org", accessControlRequestMethodHeader, http.MethodGet)
+	assert.Empty(recorder.Header().Get(accessControlAllowOriginHeader))
+	assert.Contains(recorder.Header()[varyHeader], originHeader)
+	assert.Empty(recorder.Header().Get(accessControlAllowMethodsHeader))
+	// the request is still answered as a plain OPTIONS
+	assert.Equal(http.StatusNoContent, recorder.Code)
+	assert.Equal("GET, HEAD, PUT, OPTIONS", recorder.Header().Get(allowHeader))
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	assert := assert.New(t)
+	server, controller := createCORSServer()
+	assert.NoError(server.UseCORS("", &CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Request-ID"},
+	}))
+
+	recorder := serve(server, http.MethodGet, "/api/widgets", originHeader, "https://anywhere.org")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal(http.MethodGet, controller.MethodCalled)
+	assert.Equal("*", recorder.Header().Get(accessControlAllowOriginHeader))
+	assert.Equal("X-Request-ID", recorder.Header().Get(accessControlExposeHeadersHeader))
+
+	// errors are decorated so browsers can read them
+	recorder = serve(server, http.MethodGet, "/does/not/exist", originHeader, "https://anywhere.org")
+	assert.Equal(http.StatusNotFound, recorder.Code)
+	assert.Equal("*", recorder.Header().Get(accessControlAllowOriginHeader))
+
+	// requests without an origin are not decorated
+	recorder = serve(server, http.MethodGet, "/api/widgets")
+	assert.Empty(recorder.Header().Get(accessControlAllowOriginHeader))
+}
+
+func TestCORSPerPrefix(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := createCORSServer()
+	assert.NoError(server.UseCORS("", &CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}))
+	assert.NoError(server.UseCORS("public", &CORSPolicy{AllowedOrigins: []string{"*"}}))
+	assert.Error(server.UseCORS("/public", &CORSPolicy{}))
+	assert.Error(server.UseCORS("private", &CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}))
+
+	recorder := serve(server, http.MethodGet, "/public/widgets", originHeader, "https://anywhere.org")
+	assert.Equal("*", recorder.Header().Get(accessControlAllowOriginHeader))
+
+	recorder = serve(server, http.MethodGet, "/api/widgets", originHeader, "https://anywhere.org")
+	assert.Empty(recorder.Header().Get(accessControlAllowOriginHeader))
+	assert.Contains(recorder.Header()[varyHeader], originHeader)
+
+	recorder = serve(server, http.MethodGet, "/api/widgets", originHeader, "https://app.example.com")
+	assert.Equal("https://app.example.com", recorder.Header().Get(accessControlAllowOriginHeader))
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const structuredSuffix = "+"
+
+// Decoder unmarshals a request body into the target.
+type Decoder func(body []byte, target interface{}) error
+
+var (
+	decodersMutex sync.RWMutex
+	decoders      = map[string]Decoder{
+		MediaTypeJSON:   decodeJSON,
+		contentTypeForm: decodeForm,
+		MediaTypeXML:    decodeXML,
+	}
+	// decoderAliases maps media types and structured syntax suffixes such as
+	// '+json' onto the media type of a registered decoder.
+	decoderAliases = map[string]string{
+		"text/xml": MediaTypeXML,
+		"+json":    MediaTypeJSON,
+		"+xml":     MediaTypeXML,
+	}
+)
+
+// RegisterDecoder makes the decoder available to Context.ReadObject for
+// request bodies of the passed media type, replacing any decoder already
+// registered for it.
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// RegisterDecoderAlias decodes request bodies of the alias media type with
+// the decoder registered for the passed media type. The alias may be a
+// structured syntax suffix such as '+cbor', matching vendor media types like
+// 'application/vnd.acme+cbor'. Suffixes for JSON and XML are registered by
+// default.
+func RegisterDecoderAlias(alias, mediaType string) {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	decoderAliases[strings.ToLower(alias)] = strings.ToLower(mediaType)
+}
+
+// decoderFor returns the decoder registered for the passed media type, its
+// alias, or its structured syntax suffix, in that order.
+func decoderFor(mediaType string) (Decoder, bool) {
+	mediaType = strings.ToLower(mediaType)
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder, true
+	}
+	if alias, ok := decoderAliases[mediaType]; ok {
+		decoder, ok := decoders[alias]
+		return decoder, ok
+	}
+	if i := strings.LastIndex(mediaType, structuredSuffix); i >= 0 {
+		if alias, ok := decoderAliases[mediaType[i:]]; ok {
+			decoder, ok := decoders[alias]
+			return decoder, ok
+		}
+	}
+	return nil, false
+}
+
+func decodeJSON(body []byte, target interface{}) error {
+	return json.Unmarshal(body, target)
+}
+
+func decodeXML(body []byte, target interface{}) error {
+	return xml.Unmarshal(body, target)
+}
+
+// decodeForm takes the form-urlencoded body of the Request and binds it to
+// the fields of the target named by their 'form' tag, see bindValues.
+func decodeForm(body []byte, target interface{}) error {
+	values, err := url.ParseQuery(string(body))
+	if nil != err {
+		return err
+	}
+	return bindValues(values, target, formTag)
+}
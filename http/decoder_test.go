@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+func restoreDecoders() {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	delete(decoders, "application/x-test")
+	delete(decoderAliases, "application/vnd.acme.widget")
+	delete(decoderAliases, "+test")
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestReadObjectDecoders(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string]string{
+		MediaTypeJSON:                         `{"id":1,"name":"bolt"}`,
+		MediaTypeJSON + "; charset=utf-8":     `{"id":1,"name":"bolt"}`,
+		"application/vnd.acme.widget.v2+json": `{"id":1,"name":"bolt"}`,
+		MediaTypeXML:                          `<widget><id>1</id><name>bolt</name></widget>`,
+		"text/xml":                            `<widget><id>1</id><name>bolt</name></widget>`,
+		"application/vnd.acme.widget+xml":     `<widget><id>1</id><name>bolt</name></widget>`,
+		"APPLICATION/VND.ACME.WIDGET+JSON":    `{"id":1,"name":"bolt"}`,
+	}
+	for contentType, body := range cases {
+		widget := &encodedWidget{}
+		context := createRequestContext(nil, newRequest(http.MethodPost, "/", body, contentTypeHeader, contentType))
+		assert.NoError(context.ReadObject(widget), contentType)
+		assert.Equal(&encodedWidget{ID: 1, Name: "bolt"}, widget, contentType)
+		assert.False(context.HasError())
+	}
+}
+
+func TestReadObjectUnsupported(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, newRequest(http.MethodPost, "/", "1,bolt", contentTypeHeader, "application/x-test"))
+	assert.Error(context.ReadObject(&encodedWidget{}))
+	assert.Equal(http.StatusUnsupportedMediaType, context.Status())
+	assert.Equal(qerror.UnsupportedMediaType, context.Error.Code)
+
+	for _, contentType := range []string{"", "application/"} {
+		context = createRequestContext(nil, newRequest(http.MethodPost, "/", "1,bolt", contentTypeHeader, contentType))
+		assert.Error(context.ReadObject(&encodedWidget{}))
+		assert.Equal(http.StatusUnsupportedMediaType, context.Status(), contentType)
+	}
+
+	context = createRequestContext(nil, newRequest(http.MethodPost, "/", "1,bolt", contentTypeHeader, "application/vnd.acme+cbor"))
+	assert.Error(context.ReadObject(&encodedWidget{}))
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	assert := assert.New(t)
+	defer restoreDecoders()
+	RegisterDecoder("application/x-test", func(body []byte, target interface{}) error {
+		parts := strings.Split(string(body), ",")
+		target.(*encodedWidget).Name = parts[1]
+		return nil
+	})
+	RegisterDecoderAlias("application/vnd.acme.widget", "application/x-test")
+	RegisterDecoderAlias("+test", "application/x-test")
+
+	for _, contentType := range []string{"application/x-test", "application/vnd.acme.widget",
+		"application/vnd.acme.gadget+test"} {
+		widget := &encodedWidget{}
+		assert.NoError(createRequestContext(nil, newRequest(http.MethodPost, "/", "1,bolt", contentTypeHeader, contentType)).ReadObject(widget), contentType)
+		assert.Equal("bolt", widget.Name, contentType)
+	}
+
+	// aliases of media types without a decoder are unsupported
+	RegisterDecoderAlias("+test", "application/x-missing")
+	assert.Error(createRequestContext(nil, newRequest(http.MethodPost, "/", "1,bolt", contentTypeHeader, "application/vnd.acme.gadget+test")).ReadObject(&encodedWidget{}))
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+// Media types with built in response encoders.
+const (
+	MediaTypeJSON    = contentTypeJSON
+	MediaTypeXML     = "application/xml"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeCSV     = "text/csv"
+	MediaTypeText    = "text/plain"
+)
+
+// Encoder writes a response model, or the RestError returned in its place,
+// in a media type.
+type Encoder func(w io.Writer, model interface{}) error
+
+var (
+	encodersMutex sync.RWMutex
+	encoders      = map[string]Encoder{
+		MediaTypeJSON:    encodeJSON,
+		MediaTypeXML:     encodeXML,
+		MediaTypeMsgPack: encodeMsgPack,
+		MediaTypeCSV:     encodeCSV,
+		MediaTypeText:    encodeText,
+	}
+	// encoderMediaTypes is the order of preference when the Accept header
+	// does not distinguish between media types.
+	encoderMediaTypes = []string{MediaTypeJSON, MediaTypeXML, MediaTypeMsgPack, MediaTypeCSV, MediaTypeText}
+)
+
+// RegisterEncoder makes the encoder available for responses in the passed
+// media type, replacing any encoder already registered for it. Responses
+// are encoded in the media type most preferred by the request Accept header,
+// unless the controller sets the Content-Type header.
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	mediaType = strings.ToLower(mediaType)
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+	if _, ok := encoders[mediaType]; !ok {
+		encoderMediaTypes = append(encoderMediaTypes, mediaType)
+	}
+	encoders[mediaType] = encoder
+}
+
+// encoderFor returns the encoder registered for the passed media type.
+func encoderFor(mediaType string) (Encoder, bool) {
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+	encoder, ok := encoders[strings.ToLower(mediaType)]
+	return encoder, ok
+}
+
+// negotiateEncoders returns the media types with a registered encoder that
+// are acceptable to the passed Accept header, in order of preference.
+func negotiateEncoders(accept string) []string {
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+	return acceptableMediaTypes(accept, encoderMediaTypes)
+}
+
+// rendersProblem checks if errors are rendered as problem details, either for
+// every request or when the Accept header names application/problem+json as
+// more preferred than the registered media types.
+func (server *RESTServer) rendersProblem(accept string) bool {
+	if server.ProblemDetails {
+		return true
+	}
+	encodersMutex.RLock()
+	available := append(append([]string{}, encoderMediaTypes...), qerror.ProblemMediaType)
+	encodersMutex.RUnlock()
+	mediaType, _ := negotiate(accept, available)
+	return qerror.ProblemMediaType == mediaType
+}
+
+func encodeJSON(w io.Writer, model interface{}) error {
+	b, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func encodeXML(w io.Writer, model interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(model)
+}
+
+// encodeText writes strings and byte slices as is, errors as their message,
+// and Stringers, numbers and booleans formatted with fmt. Other models cannot
+// be encoded as text.
+func encodeText(w io.Writer, model interface{}) error {
+	var err error
+	switch m := model.(type) {
+	case nil:
+	case string:
+		_, err = io.WriteString(w, m)
+	case []byte:
+		_, err = w.Write(m)
+	case *qerror.RestError:
+		_, err = io.WriteString(w, m.Message)
+	case error:
+		_, err = io.WriteString(w, m.Error())
+	case fmt.Stringer:
+		_, err = io.WriteString(w, m.String())
+	default:
+		if !isScalar(reflect.TypeOf(model)) {
+			return fmt.Errorf("cannot encode %T as text", model)
+		}
+		_, err = fmt.Fprint(w, m)
+	}
+	return err
+}
+
+// encodeCSV writes a [][]string as is, or a struct or slice of structs as a
+// header row of field names followed by a row per struct. Field names are
+// taken from 'csv' or 'json' tags if present.
+func encodeCSV(w io.Writer, model interface{}) error {
+	writer := csv.NewWriter(w)
+	if records, ok := model.([][]string); ok {
+		writer.WriteAll(records)
+		return writer.Error()
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+	rows := []reflect.Value{value}
+	if reflect.Slice == value.Kind() || reflect.Array == value.Kind() {
+		rows = rows[:0]
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, reflect.Indirect(value.Index(i)))
+		}
+	}
+	var fields []int
+	for _, row := range rows {
+		if reflect.Struct != row.Kind() {
+			return fmt.Errorf("cannot encode %T as CSV", model)
+		}
+		if nil != fields {
+			continue
+		}
+		header := []string{}
+		fields = []int{}
+		for i := 0; i < row.NumField(); i++ {
+			if name, ok := csvFieldName(row.Type().Field(i)); ok {
+				header = append(header, name)
+				fields = append(fields, i)
+			}
+		}
+		writer.Write(header)
+	}
+	for _, row := range rows {
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = fmt.Sprint(row.Field(field).Interface())
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvFieldName(field reflect.StructField) (string, bool) {
+	if "" != field.PkgPath {
+		return "", false
+	}
+	for _, key := range []string{"csv", "json"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if "-" == name {
+			return "", false
+		}
+		if "" != name {
+			return name, true
+		}
+	}
+	return field.Name, true
+}
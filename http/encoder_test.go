@@ -0,0 +1,252 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type encodedWidget struct {
+	ID     int    `json:"id" xml:"id"`
+	Name   string `json:"name" xml:"name"`
+	Secret string `json:"-" xml:"-"`
+}
+
+// modelController responds with its model, optionally setting the
+// Content-Type.
+type modelController struct {
+	TestController
+	model       interface{}
+	contentType string
+}
+
+func (controller *modelController) Get(context *Context) {
+	if "" != controller.contentType {
+		context.Response.Header().Set(contentTypeHeader, controller.contentType)
+	}
+	context.SetResponse(controller.model, http.StatusOK)
+}
+
+func encode(encoder Encoder, model interface{}) string {
+	b := &bytes.Buffer{}
+	encoder(b, model)
+	return b.String()
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestNegotiate(t *testing.T) {
+	assert := assert.New(t)
+	available := []string{MediaTypeJSON, MediaTypeXML, MediaTypeText}
+	cases := map[string]string{
+		"":                                  MediaTypeJSON,
+		"*/*":                               MediaTypeJSON,
+		"application/xml":                   MediaTypeXML,
+		"text/*":                            MediaTypeText,
+		"text/plain, application/xml":       MediaTypeText,
+		"text/plain;q=0.5, application/xml": MediaTypeXML,
+		"*/*;q=0.1, application/xml;q=0.9":  MediaTypeXML,
+		"application/json;q=0, */*":         MediaTypeXML,
+		"APPLICATION/XML; Q=0.8, text/csv":  MediaTypeXML,
+		"application/xml;q=bad, text/plain": MediaTypeText,
+		"application/xml;level=1;q=0.4, */*;q=0.3": MediaTypeXML,
+	}
+	for accept, expected := range cases {
+		mediaType, ok := negotiate(accept, available)
+		assert.True(ok, accept)
+		assert.Equal(expected, mediaType, accept)
+	}
+
+	assert.Equal([]string{MediaTypeXML, MediaTypeText, MediaTypeJSON},
+		acceptableMediaTypes("application/xml, text/*;q=0.5, */*;q=0.1", available))
+	assert.Equal(available, acceptableMediaTypes("", available))
+
+	_, ok := negotiate("image/png", available)
+	assert.False(ok)
+	_, ok = negotiate("*/*;q=0", available)
+	assert.False(ok)
+}
+
+func TestEncodeCSV(t *testing.T) {
+	assert := assert.New(t)
+	widgets := []*encodedWidget{{ID: 1, Name: "bolt"}, {ID: 2, Name: "nut, hex"}}
+	assert.Equal("id,name\n1,bolt\n2,\"nut, hex\"\n", encode(encodeCSV, widgets))
+	assert.Equal("id,name\n1,bolt\n", encode(encodeCSV, widgets[0]))
+	assert.Equal("a,b\n", encode(encodeCSV, [][]string{{"a", "b"}}))
+	assert.Error(encodeCSV(&bytes.Buffer{}, []int{1}))
+}
+
+func TestEncodeText(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("bolt", encode(encodeText, "bolt"))
+	assert.Equal("1.5", encode(encodeText, 1.5))
+	assert.Equal("failed", encode(encodeText, qerror.NewRestError(qerror.SystemError, "failed", nil)))
+	assert.Error(encodeText(&bytes.Buffer{}, &encodedWidget{ID: 1}))
+	assert.Error(encodeText(&bytes.Buffer{}, map[string]int{"id": 1}))
+}
+
+func TestEncodeMsgPack(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("\x82\xa2id\x01\xa4name\xa4bolt", encode(encodeMsgPack, &encodedWidget{ID: 1, Name: "bolt"}))
+	assert.Equal("\x93\xc0\xc3\xff", encode(encodeMsgPack, []interface{}{nil, true, -1}))
+	assert.Equal("\xd1\x01\x00", encode(encodeMsgPack, 256))
+	assert.Equal("\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00", encode(encodeMsgPack, 1.5))
+
+	long := string(make([]byte, 40))
+	assert.Equal("\xd9\x28"+long, encode(encodeMsgPack, long))
+}
+
+func TestCompleteRequestNegotiation(t *testing.T) {
+	assert := assert.New(t)
+	controller := &modelController{TestController: NewTestController("Encoding"),
+		model: &encodedWidget{ID: 1, Name: "bolt", Secret: "s"}}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodGet, "/", acceptHeader, "")
+	assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader))
+	assert.Equal(`{"id":1,"name":"bolt"}`, recorder.Body.String())
+	assert.Contains(recorder.Header()[varyHeader], acceptHeader)
+
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "application/xml, application/json;q=0.5")
+	assert.Equal(MediaTypeXML, recorder.Header().Get(contentTypeHeader))
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<encodedWidget><id>1</id><name>bolt</name></encodedWidget>`, recorder.Body.String())
+
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "text/csv")
+	assert.Equal(MediaTypeCSV, recorder.Header().Get(contentTypeHeader))
+	assert.Equal("id,name\n1,bolt\n", recorder.Body.String())
+
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "image/png")
+	assert.Equal(http.StatusNotAcceptable, recorder.Code)
+	assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader))
+	restError := &qerror.RestError{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), restError))
+	assert.Equal(qerror.NotAcceptable, restError.Code)
+}
+
+func TestCompleteRequestEncodingFailure(t *testing.T) {
+	assert := assert.New(t)
+	controller := &modelController{TestController: NewTestController("Encoding"), model: map[string]int{"id": 1}}
+	server := CreateRESTServer(":8080", controller)
+
+	// models that cannot be encoded in the preferred media type fall back to
+	// the next acceptable media type
+	for accept, mediaType := range map[string]string{
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": MediaTypeJSON,
+		"text/csv, application/msgpack":                                   MediaTypeMsgPack,
+	} {
+		recorder := serve(server, http.MethodGet, "/", acceptHeader, accept)
+		assert.Equal(http.StatusOK, recorder.Code, accept)
+		assert.Equal(mediaType, recorder.Header().Get(contentTypeHeader), accept)
+	}
+
+	// JSON is not used unless it is acceptable
+	for _, accept := range []string{"text/*", MediaTypeCSV, "application/xml, application/json;q=0"} {
+		recorder := serve(server, http.MethodGet, "/", acceptHeader, accept)
+		assert.Equal(http.StatusNotAcceptable, recorder.Code, accept)
+		assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader), accept)
+		assert.Contains(recorder.Body.String(), qerror.NotAcceptable, accept)
+	}
+
+	controller.model = make(chan int)
+	recorder := serve(server, http.MethodGet, "/", acceptHeader, MediaTypeCSV+", "+MediaTypeJSON+";q=0.5")
+	assert.Equal(http.StatusInternalServerError, recorder.Code)
+	assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader))
+	assert.Contains(recorder.Body.String(), qerror.SystemError)
+}
+
+func TestCompleteRequestContentTypeOverride(t *testing.T) {
+	assert := assert.New(t)
+	controller := &modelController{TestController: NewTestController("Encoding"),
+		model: &encodedWidget{ID: 1, Name: "bolt"}, contentType: MediaTypeCSV + "; charset=utf-8"}
+	server := CreateRESTServer(":8080", controller)
+
+	// the controller overrides the Accept header
+	recorder := serve(server, http.MethodGet, "/", acceptHeader, MediaTypeJSON)
+	assert.Equal(MediaTypeCSV+"; charset=utf-8", recorder.Header().Get(contentTypeHeader))
+	assert.Equal("id,name\n1,bolt\n", recorder.Body.String())
+
+	// unregistered media types are written as is
+	controller.contentType = "text/html"
+	controller.model = "<p>bolt</p>"
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "")
+	assert.Equal("<p>bolt</p>", recorder.Body.String())
+
+	// pre-rendered models are not encoded again
+	controller.contentType = MediaTypeJSON
+	controller.model = `{"id":1,"name":"bolt"}`
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "")
+	assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader))
+	assert.Equal(`{"id":1,"name":"bolt"}`, recorder.Body.String())
+	controller.contentType = MediaTypeXML
+	controller.model = []byte(`<encodedWidget><id>1</id></encodedWidget>`)
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "")
+	assert.Equal(MediaTypeXML, recorder.Header().Get(contentTypeHeader))
+	assert.Equal(`<encodedWidget><id>1</id></encodedWidget>`, recorder.Body.String())
+
+	// a model that cannot be written as is does not panic
+	controller.contentType = "text/html"
+	controller.model = &encodedWidget{ID: 1}
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "")
+	assert.Equal(http.StatusInternalServerError, recorder.Code)
+	assert.Contains(recorder.Body.String(), qerror.SystemError)
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	assert := assert.New(t)
+	RegisterEncoder("application/vnd.test", func(w io.Writer, model interface{}) error {
+		_, err := io.WriteString(w, "test")
+		return err
+	})
+	defer func() {
+		encodersMutex.Lock()
+		delete(encoders, "application/vnd.test")
+		encoderMediaTypes = encoderMediaTypes[:len(encoderMediaTypes)-1]
+		encodersMutex.Unlock()
+	}()
+	controller := &modelController{TestController: NewTestController("Encoding"), model: "model"}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodGet, "/", acceptHeader, "application/vnd.test")
+	assert.Equal("application/vnd.test", recorder.Header().Get(contentTypeHeader))
+	assert.Equal("test", recorder.Body.String())
+	recorder = serve(server, http.MethodGet, "/", acceptHeader, "*/*")
+	assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader))
+}
+
+func TestProblemDetails(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+
+	for _, accept := range []string{"", "*/*", "application/json, application/problem+json"} {
+		recorder := serve(server, http.MethodGet, "/missing", acceptHeader, accept)
+		assert.Equal(MediaTypeJSON, recorder.Header().Get(contentTypeHeader), accept)
+	}
+
+	recorder := serve(server, http.MethodGet, "/missing", acceptHeader, "application/problem+json, application/json;q=0.5")
+	assert.Equal(http.StatusNotFound, recorder.Code)
+	assert.Equal(qerror.ProblemMediaType, recorder.Header().Get(contentTypeHeader))
+	restError, err := qerror.DecodeRestError(recorder.Header().Get(contentTypeHeader), recorder.Body.Bytes())
+	assert.NoError(err)
+	assert.Equal(qerror.NotFound, restError.Code)
+	problem := map[string]interface{}{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(float64(http.StatusNotFound), problem["status"])
+	assert.Equal("/missing", problem["instance"])
+
+	server.ProblemDetails = true
+	recorder = serve(server, http.MethodGet, "/missing", acceptHeader, "")
+	assert.Equal(qerror.ProblemMediaType, recorder.Header().Get(contentTypeHeader))
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsRoute is the route the metrics are exposed on by the
+// MetricsController.
+const MetricsRoute = "metrics"
+
+// MetricsContentType is the content type of the Prometheus text exposition
+// format written by Metrics.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	metricsNamespace = "quimby_http"
+	unmatchedRoute   = "unmatched"
+	otherMethod      = "OTHER"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the request
+// latency histogram buckets.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds in bytes of the response size
+// histogram buckets.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Metrics records the requests handled by a RESTServer labeled by route
+// template, method, and status, and writes them in the Prometheus text
+// exposition format.
+type Metrics struct {
+	latencyBuckets []float64
+	sizeBuckets    []float64
+	inFlight       int64
+
+	mutex    sync.Mutex
+	requests map[metricLabels]*requestMetrics
+}
+
+type metricLabels struct {
+	route  string
+	method string
+	status int
+}
+
+type requestMetrics struct {
+	count   uint64
+	latency *histogram
+	size    *histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewMetrics creates Metrics using the default histogram buckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets, DefaultSizeBuckets)
+}
+
+// NewMetricsWithBuckets creates Metrics using the passed upper bounds for the
+// latency in seconds and response size in bytes histogram buckets.
+func NewMetricsWithBuckets(latencyBuckets, sizeBuckets []float64) *Metrics {
+	return &Metrics{
+		latencyBuckets: sortedBuckets(latencyBuckets),
+		sizeBuckets:    sortedBuckets(sizeBuckets),
+		requests:       make(map[metricLabels]*requestMetrics),
+	}
+}
+
+func sortedBuckets(buckets []float64) []float64 {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// started records a request being handled.
+func (metrics *Metrics) started() {
+	atomic.AddInt64(&metrics.inFlight, 1)
+}
+
+// completed records the request tracked by the passed Context.
+func (metrics *Metrics) completed(context *Context, latency time.Duration) {
+	atomic.AddInt64(&metrics.inFlight, -1)
+	labels := metricLabels{route: unmatchedRoute, method: otherMethod, status: context.Status()}
+	if nil != context.Route {
+		labels.route = context.Route.TemplateRoute
+	}
+	if containsMethod(controllerMethods, context.Method) ||
+		http.MethodHead == context.Method || http.MethodOptions == context.Method {
+		labels.method = context.Method
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	request, ok := metrics.requests[labels]
+	if !ok {
+		request = &requestMetrics{latency: newHistogram(metrics.latencyBuckets), size: newHistogram(metrics.sizeBuckets)}
+		metrics.requests[labels] = request
+	}
+	request.count++
+	request.latency.observe(latency.Seconds())
+	request.size.observe(float64(context.responseWriter().Size()))
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (metrics *Metrics) WriteTo(w io.Writer) (int64, error) {
+	metrics.mutex.Lock()
+	labels := make([]metricLabels, 0, len(metrics.requests))
+	for l := range metrics.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].route != labels[j].route {
+			return labels[i].route < labels[j].route
+		}
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].status < labels[j].status
+	})
+
+	b := &strings.Builder{}
+	writeMetricHeader(b, "requests_total", "counter", "Total number of HTTP requests handled.")
+	for _, l := range labels {
+		fmt.Fprintf(b, "%s_requests_total{%s} %d\n", metricsNamespace, l.String(), metrics.requests[l].count)
+	}
+	writeMetricHeader(b, "request_duration_seconds", "histogram", "Latency of HTTP requests in seconds.")
+	for _, l := range labels {
+		metrics.requests[l].latency.write(b, "request_duration_seconds", l)
+	}
+	writeMetricHeader(b, "response_size_bytes", "histogram", "Size of HTTP response bodies in bytes.")
+	for _, l := range labels {
+		metrics.requests[l].size.write(b, "response_size_bytes", l)
+	}
+	metrics.mutex.Unlock()
+
+	writeMetricHeader(b, "requests_in_flight", "gauge", "Number of HTTP requests being handled.")
+	fmt.Fprintf(b, "%s_requests_in_flight %d\n", metricsNamespace, atomic.LoadInt64(&metrics.inFlight))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", metricsNamespace, name, help, metricsNamespace, name, kind)
+}
+
+func (h *histogram) write(b *strings.Builder, name string, labels metricLabels) {
+	for i, bound := range h.buckets {
+		fmt.Fprintf(b, "%s_%s_bucket{%s,le=\"%s\"} %d\n", metricsNamespace, name, labels.String(),
+			formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_%s_bucket{%s,le=\"+Inf\"} %d\n", metricsNamespace, name, labels.String(), h.count)
+	fmt.Fprintf(b, "%s_%s_sum{%s} %s\n", metricsNamespace, name, labels.String(), formatFloat(h.sum))
+	fmt.Fprintf(b, "%s_%s_count{%s} %d\n", metricsNamespace, name, labels.String(), h.count)
+}
+
+func (labels metricLabels) String() string {
+	return fmt.Sprintf("route=\"%s\",method=\"%s\",status=\"%d\"", escapeLabel(labels.route),
+		escapeLabel(labels.method), labels.status)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
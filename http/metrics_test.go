@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+func writeMetrics(metrics *Metrics) string {
+	b := &bytes.Buffer{}
+	metrics.WriteTo(b)
+	return b.String()
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestMetricsRecordsRouteTemplate(t *testing.T) {
+	assert := assert.New(t)
+	controller := &declaredMethodsController{TestController: NewTestController("Metrics")}
+	server := CreateRESTServer(":8080", nil)
+	assert.NoError(server.Router.AddRoute("widgets/{{id}}", controller))
+	server.Metrics = NewMetricsWithBuckets([]float64{60}, []float64{1, 1000})
+
+	serve(server, http.MethodGet, "/widgets/1")
+	serve(server, http.MethodGet, "/widgets/2")
+	serve(server, http.MethodPost, "/widgets/2")
+	serve(server, "BREW", "/widgets/2")
+	serve(server, http.MethodGet, "/does/not/exist")
+
+	output := writeMetrics(server.Metrics)
+	assert.Contains(output, "# TYPE quimby_http_requests_total counter\n")
+	assert.Contains(output, `quimby_http_requests_total{route="widgets/{{id}}",method="GET",status="200"} 2`+"\n")
+	assert.Contains(output, `quimby_http_requests_total{route="widgets/{{id}}",method="POST",status="405"} 1`+"\n")
+	assert.Contains(output, `quimby_http_requests_total{route="widgets/{{id}}",method="OTHER",status="405"} 1`+"\n")
+	assert.Contains(output, `quimby_http_requests_total{route="unmatched",method="GET",status="404"} 1`+"\n")
+	assert.NotContains(output, "widgets/1")
+
+	assert.Contains(output, "# TYPE quimby_http_request_duration_seconds histogram\n")
+	assert.Contains(output,
+		`quimby_http_request_duration_seconds_bucket{route="widgets/{{id}}",method="GET",status="200",le="60"} 2`+"\n")
+	assert.Contains(output,
+		`quimby_http_request_duration_seconds_bucket{route="widgets/{{id}}",method="GET",status="200",le="+Inf"} 2`+"\n")
+	assert.Contains(output,
+		`quimby_http_request_duration_seconds_count{route="widgets/{{id}}",method="GET",status="200"} 2`+"\n")
+
+	assert.Contains(output,
+		`quimby_http_response_size_bytes_bucket{route="widgets/{{id}}",method="GET",status="200",le="1"} 0`+"\n")
+	assert.Contains(output,
+		`quimby_http_response_size_bytes_bucket{route="widgets/{{id}}",method="GET",status="200",le="1000"} 2`+"\n")
+	assert.Contains(output, "quimby_http_requests_in_flight 0\n")
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	assert := assert.New(t)
+	controller := newBlockingController()
+	server := CreateRESTServer(":8080", controller)
+	server.Metrics = NewMetrics()
+
+	done := make(chan bool)
+	go func() {
+		serve(server, http.MethodGet, "/")
+		done <- true
+	}()
+	<-controller.started
+	assert.Contains(writeMetrics(server.Metrics), "quimby_http_requests_in_flight 1\n")
+	controller.release <- true
+	<-done
+	assert.Contains(writeMetrics(server.Metrics), "quimby_http_requests_in_flight 0\n")
+}
+
+func TestMetricsOrdering(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("Metrics")
+	server := CreateRESTServer(":8080", nil)
+	server.Router.AddRoute("b", &controller)
+	server.Router.AddRoute("a", &controller)
+	server.Metrics = NewMetrics()
+	serve(server, http.MethodGet, "/b")
+	serve(server, http.MethodGet, "/a")
+
+	output := writeMetrics(server.Metrics)
+	assert.True(strings.Index(output, `{route="a"`) < strings.Index(output, `{route="b"`))
+	assert.Equal(output, writeMetrics(server.Metrics))
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(`a\"b\\c\nd`, escapeLabel("a\"b\\c\nd"))
+}
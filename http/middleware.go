@@ -0,0 +1,106 @@
+package http
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kasita-Inc/gadget/stringutil"
+)
+
+// Handler processes a request using the state tracked on the Context.
+type Handler func(context *Context)
+
+// Middleware wraps the handling of a request. Middleware may act on the
+// Context before and after calling next, or short-circuit the request by
+// setting an error on the Context and returning without calling next.
+type Middleware func(context *Context, next Handler)
+
+// MiddlewareController can be implemented by a Controller that requires
+// middleware to run around its own methods.
+type MiddlewareController interface {
+	Middleware() []Middleware
+}
+
+type prefixMiddleware struct {
+	prefix     []string
+	middleware []Middleware
+}
+
+// Use registers middleware to run for every request handled by the server.
+// Middleware runs in the order registered, before any route prefix or
+// controller middleware.
+func (server *RESTServer) Use(middleware ...Middleware) {
+	server.middleware = append(server.middleware, middleware...)
+}
+
+// UsePrefix registers middleware to run for requests whose path begins with
+// the passed route prefix. Prefixes match whole path segments and template
+// segments such as '{{id}}' match any value. Prefix middleware runs after
+// global middleware, shorter prefixes first, then in the order registered.
+func (server *RESTServer) UsePrefix(prefix string, middleware ...Middleware) error {
+	splitPrefix, err := splitPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	server.prefixMiddleware = append(server.prefixMiddleware,
+		prefixMiddleware{prefix: splitPrefix, middleware: middleware})
+	sort.SliceStable(server.prefixMiddleware, func(i, j int) bool {
+		return len(server.prefixMiddleware[i].prefix) < len(server.prefixMiddleware[j].prefix)
+	})
+	return nil
+}
+
+func splitPrefix(prefix string) ([]string, error) {
+	prefix = strings.TrimSpace(prefix)
+	splitPrefix := strings.Split(prefix, Slash)
+	if len(stringutil.Clean(splitPrefix)) != len(splitPrefix) {
+		return nil, fmt.Errorf("Invalid route prefix format '%s'. Remove leading, "+
+			"trailing, and double slashes", prefix)
+	}
+	return splitPrefix, nil
+}
+
+// hasPrefix checks if the passed path begins with all the segments of the
+// passed prefix.
+func hasPrefix(path []string, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i, segment := range prefix {
+		if !strings.HasPrefix(segment, stringutil.DOpen) && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// middlewareFor returns the global, route prefix, and controller middleware
+// applicable to the request tracked by the passed Context, in order.
+func (server *RESTServer) middlewareFor(context *Context) []Middleware {
+	middleware := append([]Middleware{}, server.middleware...)
+	path := strings.Split(cleanPath(context.URI), Slash)
+	for _, registered := range server.prefixMiddleware {
+		if hasPrefix(path, registered.prefix) {
+			middleware = append(middleware, registered.middleware...)
+		}
+	}
+	if nil != context.Route {
+		if controller, ok := context.Route.Controller.(MiddlewareController); ok {
+			middleware = append(middleware, controller.Middleware()...)
+		}
+	}
+	return middleware
+}
+
+// chain wraps the handler in the passed middleware so that the first
+// middleware is the outermost.
+func chain(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		current, next := middleware[i], handler
+		handler = func(context *Context) {
+			current(context, next)
+		}
+	}
+	return handler
+}
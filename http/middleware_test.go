@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type middlewareTestController struct {
+	TestController
+	middleware []Middleware
+}
+
+func (controller *middlewareTestController) Middleware() []Middleware {
+	return controller.middleware
+}
+
+// recordingMiddleware appends the passed name to calls before and after
+// calling next.
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(context *Context, next Handler) {
+		*calls = append(*calls, name)
+		next(context)
+		*calls = append(*calls, name+" done")
+	}
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestMiddlewareOrdering(t *testing.T) {
+	assert := assert.New(t)
+	calls := []string{}
+	controller := &middlewareTestController{TestController: NewTestController("Middleware")}
+	controller.middleware = []Middleware{recordingMiddleware("controller", &calls)}
+	server := CreateRESTServer(":8080", nil)
+	assert.NoError(server.Router.AddRoute("api/widgets/{{id}}", controller))
+
+	assert.NoError(server.UsePrefix("api/widgets/{{id}}", recordingMiddleware("widget", &calls)))
+	assert.NoError(server.UsePrefix("api", recordingMiddleware("api", &calls)))
+	assert.NoError(server.UsePrefix("other", recordingMiddleware("other", &calls)))
+	server.Use(recordingMiddleware("global1", &calls), recordingMiddleware("global2", &calls))
+
+	recorder := serve(server, http.MethodGet, "/api/widgets/1")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal(http.MethodGet, controller.MethodCalled)
+	assert.Equal([]string{
+		"global1", "global2", "api", "widget", "controller",
+		"controller done", "widget done", "api done", "global2 done", "global1 done",
+	}, calls)
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	assert := assert.New(t)
+	calls := []string{}
+	controller := NewTestController("Middleware")
+	server := CreateRESTServer(":8080", &controller)
+	server.Use(func(context *Context, next Handler) {
+		context.SetError(qerror.NewRestError(qerror.NotAuthorized, "", nil), http.StatusForbidden)
+	})
+	server.Use(recordingMiddleware("never", &calls))
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusForbidden, recorder.Code)
+	assert.Contains(recorder.Body.String(), qerror.NotAuthorized)
+	assert.Empty(calls)
+	assert.Equal("", controller.MethodCalled)
+}
+
+func TestMiddlewareAfterNext(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("Middleware")
+	server := CreateRESTServer(":8080", &controller)
+	server.Use(func(context *Context, next Handler) {
+		next(context)
+		context.Response.Header().Set("X-Status", http.StatusText(context.Status()))
+	})
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusText(http.StatusOK), recorder.Header().Get("X-Status"))
+}
+
+func TestMiddlewareRunsForRoutingErrors(t *testing.T) {
+	assert := assert.New(t)
+	calls := []string{}
+	controller := NewTestController("Middleware")
+	server := CreateRESTServer(":8080", &controller)
+	server.Use(recordingMiddleware("global", &calls))
+
+	recorder := serve(server, http.MethodGet, "/does/not/exist")
+	assert.NotEqual(http.StatusOK, recorder.Code)
+	assert.Equal([]string{"global", "global done"}, calls)
+}
+
+func TestUsePrefixInvalid(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	assert.Error(server.UsePrefix("/api", recordingMiddleware("api", &[]string{})))
+	assert.Error(server.UsePrefix("api/", recordingMiddleware("api", &[]string{})))
+	assert.Error(server.UsePrefix("", recordingMiddleware("api", &[]string{})))
+}
+
+func TestHasPrefix(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(hasPrefix([]string{"api", "widgets", "1"}, []string{"api"}))
+	assert.True(hasPrefix([]string{"api", "widgets", "1"}, []string{"api", "{{any}}", "1"}))
+	assert.False(hasPrefix([]string{"api"}, []string{"api", "widgets"}))
+	assert.False(hasPrefix([]string{"apis", "widgets"}, []string{"api"}))
+}
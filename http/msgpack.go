@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// encodeMsgPack writes the model in the MessagePack format. The model is
+// first converted through its JSON representation so 'json' struct tags and
+// json.Marshaler implementations are respected.
+func encodeMsgPack(w io.Writer, model interface{}) error {
+	b, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return err
+	}
+	buffer := &bytes.Buffer{}
+	if err = writeMsgPack(buffer, value); err != nil {
+		return err
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+func writeMsgPack(b *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		b.WriteByte(0xc0)
+	case bool:
+		if v {
+			b.WriteByte(0xc3)
+		} else {
+			b.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeMsgPackInt(b, i)
+		} else if f, err := v.Float64(); err == nil {
+			b.WriteByte(0xcb)
+			binary.Write(b, binary.BigEndian, math.Float64bits(f))
+		} else {
+			return err
+		}
+	case string:
+		writeMsgPackHeader(b, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		b.WriteString(v)
+	case []interface{}:
+		writeMsgPackHeader(b, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, element := range v {
+			if err := writeMsgPack(b, element); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeMsgPackHeader(b, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			writeMsgPack(b, key)
+			if err := writeMsgPack(b, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as MessagePack", value)
+	}
+	return nil
+}
+
+// writeMsgPackHeader writes the type and length of a string, array, or map
+// using the fix format if the length is below fixLimit, otherwise the 8 (if
+// supported), 16, or 32 bit format.
+func writeMsgPackHeader(b *bytes.Buffer, length int, fix byte, fixLimit int, format8, format16, format32 byte) {
+	switch {
+	case length < fixLimit:
+		b.WriteByte(fix | byte(length))
+	case 0 != format8 && length <= math.MaxUint8:
+		b.WriteByte(format8)
+		b.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		b.WriteByte(format16)
+		binary.Write(b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(format32)
+		binary.Write(b, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMsgPackInt(b *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		b.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		b.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		b.WriteByte(0xd0)
+		b.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		b.WriteByte(0xd1)
+		binary.Write(b, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		b.WriteByte(0xd2)
+		binary.Write(b, binary.BigEndian, int32(i))
+	default:
+		b.WriteByte(0xd3)
+		binary.Write(b, binary.BigEndian, i)
+	}
+}
@@ -0,0 +1,281 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"reflect"
+
+	"github.com/Kasita-Inc/gadget/log"
+	"github.com/Kasita-Inc/gadget/stringutil"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+const (
+	contentTypeMultipart = "multipart/form-data"
+	formTag              = "form"
+	requiredOption       = "required"
+	uploadFilePrefix     = "quimby-upload-"
+)
+
+// DefaultMultipartMemory is the number of bytes of uploaded files held in
+// memory before files are written to temporary files.
+const DefaultMultipartMemory = 10 << 20
+
+// MultipartLimits configures how multipart/form-data bodies are read by
+// Context.ReadMultipart.
+type MultipartLimits struct {
+	// MaxMemory is the number of bytes of uploaded files held in memory,
+	// beyond which files are streamed to temporary files. Defaults to
+	// DefaultMultipartMemory.
+	MaxMemory int64
+	// MaxFileSize is the size in bytes of the largest file accepted, zero
+	// accepts files of any size.
+	MaxFileSize int64
+	// MaxTotalSize is the total size in bytes of the values and files
+	// accepted, zero accepts bodies of any size.
+	MaxTotalSize int64
+}
+
+// FormFile is a file uploaded in a multipart/form-data body. Files are
+// removed when the request completes, Open should not be used after.
+type FormFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+
+	content []byte
+	path    string
+}
+
+// Open returns a reader for the content of the file.
+func (file *FormFile) Open() (io.ReadCloser, error) {
+	if "" != file.path {
+		return os.Open(file.path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(file.content)), nil
+}
+
+var (
+	formFileType  = reflect.TypeOf(&FormFile{})
+	formFilesType = reflect.TypeOf([]*FormFile{})
+)
+
+// FormFile returns the first file uploaded as the passed form field by
+// ReadMultipart, or nil if there is none.
+func (context *Context) FormFile(name string) *FormFile {
+	if files := context.formFiles[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+func (context *Context) multipartLimits() MultipartLimits {
+	limits := MultipartLimits{}
+	if nil != context.server && nil != context.server.MultipartLimits {
+		limits = *context.server.MultipartLimits
+	}
+	if limits.MaxMemory <= 0 {
+		limits.MaxMemory = DefaultMultipartMemory
+	}
+	return limits
+}
+
+// ReadMultipart reads a multipart/form-data body, binding values to the
+// fields of the target as ReadObject does for form bodies. Files are bound
+// to fields of type *FormFile or []*FormFile named by their 'form' tag, or
+// the underscored field name. A 'required' tag option such as
+// `form:"firmware,required"` reports a FieldError if the part is missing, as
+// do files larger than the MultipartLimits on the server. The target is then
+// validated, see Validate.
+func (context *Context) ReadMultipart(target interface{}) error {
+	if err := context.readMultipart(target); nil != err {
+		return err
+	}
+	return context.validate(target)
+}
+
+func (context *Context) readMultipart(target interface{}) error {
+	reader, err := context.Request.MultipartReader()
+	if nil != err {
+		context.setBindError(err)
+		return err
+	}
+	limits := context.multipartLimits()
+	values := url.Values{}
+	context.formFiles = map[string][]*FormFile{}
+	var total, memory int64
+	for {
+		part, err := reader.NextPart()
+		if io.EOF == err {
+			break
+		}
+		if nil != err {
+			context.setBodyError(err)
+			return err
+		}
+		name := part.FormName()
+		if "" == name {
+			part.Close()
+			continue
+		}
+		remaining := int64(-1)
+		if limits.MaxTotalSize > 0 {
+			remaining = limits.MaxTotalSize - total
+		}
+
+		if "" == part.FileName() {
+			b, err := ioutil.ReadAll(limitReader(part, remaining))
+			if nil != err {
+				context.setBodyError(err)
+				return err
+			}
+			if remaining >= 0 && int64(len(b)) > remaining {
+				return context.multipartTooLarge(limits)
+			}
+			total += int64(len(b))
+			values.Add(name, string(b))
+			continue
+		}
+
+		file, err := context.readFormFile(part, limitReader(part, minLimit(limits.MaxFileSize, remaining)),
+			limits.MaxMemory-memory)
+		if errors.Is(err, ErrBodyTooLarge) {
+			context.bodyTooLarge()
+			return err
+		}
+		if nil != err {
+			context.Fail(qerror.SystemError)
+			return err
+		}
+		if limits.MaxFileSize > 0 && file.Size > limits.MaxFileSize {
+			context.AddError(newFieldError(qerror.FileTooLarge, name, context.languages(), file.Filename,
+				limits.MaxFileSize))
+			continue
+		}
+		if remaining >= 0 && file.Size > remaining {
+			return context.multipartTooLarge(limits)
+		}
+		total += file.Size
+		if "" == file.path {
+			memory += file.Size
+		}
+		context.formFiles[name] = append(context.formFiles[name], file)
+	}
+
+	if err = bindValues(values, target, formTag, context.languages()...); nil != err {
+		context.setBindError(err)
+	}
+	context.bindFormFiles(target, values)
+	if context.HasError() {
+		return context.Error
+	}
+	return nil
+}
+
+func (context *Context) multipartTooLarge(limits MultipartLimits) error {
+	context.Fail(qerror.RequestTooLarge, limits.MaxTotalSize)
+	return context.Error
+}
+
+// readFormFile reads the file part, holding up to the passed number of
+// bytes in memory before streaming the file to a temporary file.
+func (context *Context) readFormFile(part *multipart.Part, reader io.Reader, memory int64) (*FormFile, error) {
+	file := &FormFile{
+		Filename:    part.FileName(),
+		ContentType: part.Header.Get(contentTypeHeader),
+		Header:      part.Header,
+	}
+	buffer := &bytes.Buffer{}
+	n, err := io.CopyN(buffer, reader, memory+1)
+	if nil != err && io.EOF != err {
+		return nil, err
+	}
+	if n <= memory {
+		file.content = buffer.Bytes()
+		file.Size = n
+		return file, nil
+	}
+
+	tmp, err := ioutil.TempFile("", uploadFilePrefix)
+	if nil != err {
+		return nil, err
+	}
+	file.path = tmp.Name()
+	context.uploads = append(context.uploads, file.path)
+	file.Size, err = io.Copy(tmp, io.MultiReader(buffer, reader))
+	if closeErr := tmp.Close(); nil == err {
+		err = closeErr
+	}
+	return file, err
+}
+
+// bindFormFiles sets the file fields of the target and reports missing
+// required parts.
+func (context *Context) bindFormFiles(target interface{}, values url.Values) {
+	v := reflect.Indirect(reflect.ValueOf(target))
+	if reflect.Struct != v.Kind() {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if "" != field.PkgPath {
+			continue
+		}
+		name, options := taggedName(field, formTag)
+		required := hasOption(options, requiredOption)
+		files := context.formFiles[name]
+		switch field.Type {
+		case formFileType:
+			if len(files) > 0 {
+				v.Field(i).Set(reflect.ValueOf(files[0]))
+			}
+		case formFilesType:
+			if len(files) > 0 {
+				v.Field(i).Set(reflect.ValueOf(files))
+			}
+		}
+		if required && 0 == len(files) && stringutil.IsEmpty(values.Get(name)) {
+			context.AddError(newFieldError(qerror.CannotBeBlank, name, context.languages(), name))
+		}
+	}
+}
+
+// removeUploads removes the temporary files written for uploaded files.
+func (context *Context) removeUploads() {
+	for _, path := range context.uploads {
+		if err := os.Remove(path); nil != err && !os.IsNotExist(err) {
+			log.Errorf("failed to remove uploaded file '%s': %s", path, err)
+		}
+	}
+	context.uploads = nil
+}
+
+// limitReader limits the reader to one byte more than the passed limit, so
+// that exceeding the limit can be detected. A negative limit is unlimited.
+func limitReader(reader io.Reader, limit int64) io.Reader {
+	if limit < 0 {
+		return reader
+	}
+	return io.LimitReader(reader, limit+1)
+}
+
+// minLimit returns the smaller of the passed limits, where a limit of zero
+// or less is unlimited. It returns -1 if both are unlimited.
+func minLimit(a, b int64) int64 {
+	switch {
+	case a <= 0 && b < 0:
+		return -1
+	case a <= 0:
+		return b
+	case b < 0 || a < b:
+		return a
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type firmwareUpload struct {
+	Version  string
+	Notes    string
+	Firmware *FormFile   `form:"firmware,required"`
+	Images   []*FormFile `form:"image"`
+}
+
+// multipartRequest creates a multipart/form-data request with the passed
+// values and files keyed by field name.
+func multipartRequest(values map[string]string, files map[string][]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range values {
+		writer.WriteField(name, value)
+	}
+	for name, contents := range files {
+		for i, content := range contents {
+			part, _ := writer.CreateFormFile(name, name+strings.Repeat("_", i)+".bin")
+			part.Write([]byte(content))
+		}
+	}
+	writer.Close()
+	request := httptest.NewRequest(http.MethodPost, "/", body)
+	request.Header.Set(contentTypeHeader, writer.FormDataContentType())
+	return request
+}
+
+func readFormFile(file *FormFile) string {
+	reader, err := file.Open()
+	if err != nil {
+		return err.Error()
+	}
+	defer reader.Close()
+	b, _ := ioutil.ReadAll(reader)
+	return string(b)
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestReadMultipart(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, multipartRequest(map[string]string{"version": "1.2.3", "notes": "fixes"}, map[string][]string{"firmware": {"binary"}, "image": {"one", "two"}}))
+
+	upload := &firmwareUpload{}
+	assert.NoError(context.ReadObject(upload))
+	assert.False(context.HasError())
+	assert.Equal("1.2.3", upload.Version)
+	assert.Equal("fixes", upload.Notes)
+	if assert.NotNil(upload.Firmware) {
+		assert.Equal("firmware.bin", upload.Firmware.Filename)
+		assert.Equal(int64(len("binary")), upload.Firmware.Size)
+		assert.Equal("binary", readFormFile(upload.Firmware))
+	}
+	if assert.Len(upload.Images, 2) {
+		assert.Equal("one", readFormFile(upload.Images[0]))
+		assert.Equal("two", readFormFile(upload.Images[1]))
+	}
+	assert.Equal(upload.Firmware, context.FormFile("firmware"))
+	assert.Nil(context.FormFile("missing"))
+	assert.Empty(context.uploads)
+}
+
+func TestReadMultipartSpillsToDisk(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	server.MultipartLimits = &MultipartLimits{MaxMemory: 8}
+	context := createRequestContext(server, multipartRequest(nil, map[string][]string{"firmware": {"small"}, "image": {"larger than memory", "also on disk"}}))
+
+	upload := &firmwareUpload{}
+	assert.NoError(context.ReadMultipart(upload))
+	assert.Equal("small", readFormFile(upload.Firmware))
+	assert.Empty(upload.Firmware.path)
+	if assert.Len(upload.Images, 2) && assert.Len(context.uploads, 2) {
+		assert.Equal("larger than memory", readFormFile(upload.Images[0]))
+		assert.Equal("also on disk", readFormFile(upload.Images[1]))
+		assert.Equal(context.uploads[0], upload.Images[0].path)
+	}
+
+	uploads := context.uploads
+	context.removeUploads()
+	for _, path := range uploads {
+		_, err := os.Stat(path)
+		assert.True(os.IsNotExist(err))
+	}
+}
+
+func TestReadMultipartFieldErrors(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	server.MultipartLimits = &MultipartLimits{MaxFileSize: 4}
+	context := createRequestContext(server, multipartRequest(map[string]string{"version": "1"}, map[string][]string{"image": {"too large"}}))
+
+	upload := &firmwareUpload{}
+	assert.Error(context.ReadMultipart(upload))
+	assert.Equal(http.StatusBadRequest, context.Status())
+	assert.Equal(qerror.ValidationError, context.Error.Code)
+	assert.Equal(map[string]string{
+		"image":    qerror.FileTooLarge,
+		"firmware": qerror.CannotBeBlank,
+	}, fieldErrorCodes(context.Error))
+	assert.Empty(upload.Images)
+}
+
+func TestReadMultipartTotalSize(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	server.MultipartLimits = &MultipartLimits{MaxTotalSize: 10}
+
+	context := createRequestContext(server, multipartRequest(map[string]string{"version": "1.2.3.4"}, map[string][]string{"firmware": {"12345"}}))
+	assert.Error(context.ReadMultipart(&firmwareUpload{}))
+	assert.Equal(http.StatusRequestEntityTooLarge, context.Status())
+	assert.Equal(qerror.RequestTooLarge, context.Error.Code)
+
+	context = createRequestContext(server, multipartRequest(map[string]string{"version": "1.2"}, map[string][]string{"firmware": {"12345"}}))
+	assert.NoError(context.ReadMultipart(&firmwareUpload{}))
+}
+
+func TestReadMultipartBodyTooLarge(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	server.MaxBodySize = 256
+	server.MultipartLimits = &MultipartLimits{MaxFileSize: 4}
+
+	// the body limit is hit draining a file skipped as too large
+	request := multipartRequest(map[string]string{"version": "1"},
+		map[string][]string{"firmware": {strings.Repeat("x", 512)}})
+	request.ContentLength = -1
+	context := createRequestContext(server, request)
+	assert.Error(context.ReadMultipart(&firmwareUpload{}))
+	assert.Equal(http.StatusRequestEntityTooLarge, context.Status())
+	assert.Equal(qerror.RequestTooLarge, context.Error.Code)
+}
+
+func TestReadMultipartNotMultipart(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, newRequest(http.MethodPost, "/", "{}", contentTypeHeader, MediaTypeJSON))
+	assert.Error(context.ReadMultipart(&firmwareUpload{}))
+	assert.Equal(http.StatusNotAcceptable, context.Status())
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	acceptHeader         = "Accept"
+	acceptLanguageHeader = "Accept-Language"
+)
+
+// qualityValue is an entry of a header such as Accept weighted by its
+// q-value, e.g. 'text/html;q=0.8'.
+type qualityValue struct {
+	value string
+	q     float64
+	// index is the position of the entry in the header
+	index int
+}
+
+// parseQualityValues parses a comma separated header of values weighted by
+// q-values, ordered by descending q-value then position in the header.
+// Entries with malformed q-values are ignored.
+func parseQualityValues(header string) []qualityValue {
+	values := []qualityValue{}
+	for i, entry := range strings.Split(header, ",") {
+		params := strings.Split(entry, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if "" == value {
+			continue
+		}
+		q := 1.0
+		valid := true
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(strings.ToLower(param), "q=") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			values = append(values, qualityValue{value: value, q: q, index: i})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].q > values[j].q
+	})
+	return values
+}
+
+// mediaRangeMatches checks if the media type matches the media range, such
+// as 'text/*' or '*/*', returning the specificity of the match.
+func mediaRangeMatches(mediaRange, mediaType string) (int, bool) {
+	if mediaRange == mediaType {
+		return 2, true
+	}
+	rangeType := strings.SplitN(mediaRange, "/", 2)
+	if "*/*" == mediaRange || "*" == mediaRange {
+		return 0, true
+	}
+	if 2 == len(rangeType) && "*" == rangeType[1] && strings.HasPrefix(mediaType, rangeType[0]+"/") {
+		return 1, true
+	}
+	return 0, false
+}
+
+// negotiate returns the available media type most preferred by the passed
+// Accept header. It returns false if no media type is acceptable.
+func negotiate(accept string, available []string) (string, bool) {
+	if acceptable := acceptableMediaTypes(accept, available); len(acceptable) > 0 {
+		return acceptable[0], true
+	}
+	return "", false
+}
+
+// acceptableMediaTypes returns the available media types acceptable to the
+// passed Accept header in order of preference. Each media type is weighted by
+// the most specific range matching it, ties are broken by position in the
+// header and then by the order of the available media types.
+func acceptableMediaTypes(accept string, available []string) []string {
+	if "" == strings.TrimSpace(accept) {
+		return append([]string{}, available...)
+	}
+	ranges := parseQualityValues(accept)
+	acceptable := []qualityValue{}
+	for _, mediaType := range available {
+		specificity, q, index := -1, 0.0, 0
+		for _, r := range ranges {
+			if s, ok := mediaRangeMatches(r.value, mediaType); ok && s > specificity {
+				specificity, q, index = s, r.q, r.index
+			}
+		}
+		if specificity >= 0 && q > 0 {
+			acceptable = append(acceptable, qualityValue{value: mediaType, q: q, index: index})
+		}
+	}
+	sort.SliceStable(acceptable, func(i, j int) bool {
+		return acceptable[i].q > acceptable[j].q ||
+			(acceptable[i].q == acceptable[j].q && acceptable[i].index < acceptable[j].index)
+	})
+	mediaTypes := make([]string, len(acceptable))
+	for i, mediaType := range acceptable {
+		mediaTypes[i] = mediaType.value
+	}
+	return mediaTypes
+}
+
+// acceptedLanguages returns the language tags of an Accept-Language header in
+// order of preference, excluding the wildcard and refused languages.
+func acceptedLanguages(header string) []string {
+	languages := []string{}
+	for _, language := range parseQualityValues(header) {
+		if language.q > 0 && "*" != language.value {
+			languages = append(languages, language.value)
+		}
+	}
+	return languages
+}
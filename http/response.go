@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+const contentLengthHeader = "Content-Length"
+
+// bodyAllowed checks if a response with the passed status may include a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// writeResponse writes the status and the passed body of the response. If
+// the controller already wrote to the response the status cannot be changed
+// and only the body is appended.
+func writeResponse(context *Context, body []byte) {
+	writer := context.responseWriter()
+	if !writer.Written() {
+		status := context.responseStatus
+		if 0 == status {
+			status = http.StatusOK
+		}
+		writer.WriteHeader(status)
+	}
+	if bodyAllowed(writer.Status()) && len(body) > 0 {
+		writer.Write(body)
+	}
+}
+
+// ResponseWriter wraps the http.ResponseWriter of a request, tracking the
+// status and number of bytes written. The response to a HEAD request has
+// its body discarded and its header held until the request completes, so
+// the Content-Length of the discarded body can be reported.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	size      int
+	head      bool
+	discarded int
+}
+
+func newResponseWriter(writer http.ResponseWriter, request *http.Request) *ResponseWriter {
+	return &ResponseWriter{
+		ResponseWriter: writer,
+		head:           nil != request && http.MethodHead == request.Method,
+	}
+}
+
+// WriteHeader sends the header with the passed status. Only the first call
+// has an effect.
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.Written() {
+		return
+	}
+	w.status = status
+	if !w.head {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Write writes the body of the response, sending the header with status OK
+// first if it has not been written.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if !w.Written() {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.head {
+		w.discarded += len(b)
+		return len(b), nil
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Written returns true if the header has been written.
+func (w *ResponseWriter) Written() bool {
+	return 0 != w.status
+}
+
+// Status returns the status written, or zero if the header has not been
+// written.
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of body bytes written.
+func (w *ResponseWriter) Size() int {
+	return w.size
+}
+
+// Flush sends any buffered data to the client, see http.Flusher.
+func (w *ResponseWriter) Flush() {
+	if w.head {
+		return
+	}
+	if !w.Written() {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, see http.Hijacker.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", w.ResponseWriter)
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// complete writes the header held for a HEAD request to the wrapped
+// ResponseWriter.
+func (w *ResponseWriter) complete() {
+	if !w.head {
+		return
+	}
+	if 0 == w.status {
+		w.status = http.StatusOK
+	}
+	header := w.ResponseWriter.Header()
+	if bodyAllowed(w.status) && "" == header.Get(contentLengthHeader) {
+		header.Set(contentLengthHeader, strconv.Itoa(w.discarded))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
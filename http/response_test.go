@@ -0,0 +1,145 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+// countingRecorder counts the calls to WriteHeader.
+type countingRecorder struct {
+	*httptest.ResponseRecorder
+	writeHeaderCalls int
+}
+
+func (recorder *countingRecorder) WriteHeader(status int) {
+	recorder.writeHeaderCalls++
+	recorder.ResponseRecorder.WriteHeader(status)
+}
+
+// writingController writes directly to the response, optionally setting a
+// status first and an error after.
+type writingController struct {
+	TestController
+	status int
+	err    bool
+}
+
+func (controller *writingController) Get(context *Context) {
+	if 0 != controller.status {
+		context.Response.WriteHeader(controller.status)
+	}
+	context.Write("written")
+	if controller.err {
+		context.SetError(qerror.NewRestError(qerror.SystemError, "", nil), http.StatusInternalServerError)
+	}
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestResponseWriterTracking(t *testing.T) {
+	assert := assert.New(t)
+	recorder := httptest.NewRecorder()
+	writer := newResponseWriter(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(writer.Written())
+
+	writer.Write([]byte("abc"))
+	assert.True(writer.Written())
+	assert.Equal(http.StatusOK, writer.Status())
+	writer.WriteHeader(http.StatusTeapot)
+	writer.Write([]byte("de"))
+	assert.Equal(http.StatusOK, writer.Status())
+	assert.Equal(5, writer.Size())
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("abcde", recorder.Body.String())
+
+	writer.Flush()
+	assert.True(recorder.Flushed)
+	assert.Equal(recorder, writer.Unwrap())
+	_, _, err := writer.Hijack()
+	assert.Error(err)
+}
+
+func TestResponseWriterHead(t *testing.T) {
+	assert := assert.New(t)
+	recorder := httptest.NewRecorder()
+	writer := newResponseWriter(recorder, httptest.NewRequest(http.MethodHead, "/", nil))
+	writer.WriteHeader(http.StatusAccepted)
+	writer.Write([]byte("abc"))
+	assert.Equal(0, writer.Size())
+	assert.False(recorder.Flushed)
+
+	writer.complete()
+	assert.Equal(http.StatusAccepted, recorder.Code)
+	assert.Equal("3", recorder.Header().Get(contentLengthHeader))
+	assert.Empty(recorder.Body.String())
+}
+
+func TestServeHTTPWrittenResponse(t *testing.T) {
+	assert := assert.New(t)
+	controller := &writingController{TestController: NewTestController("Writing")}
+	server := CreateRESTServer(":8080", controller)
+	logger := &recordingAccessLogger{}
+	server.AccessLogger = logger
+
+	recorder := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	server.ServeHTTP(recorder, newRequest(http.MethodGet, "/", ""))
+	assert.Equal(1, recorder.writeHeaderCalls)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("written", recorder.Body.String())
+
+	controller.status = http.StatusCreated
+	recorder = &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	server.ServeHTTP(recorder, newRequest(http.MethodGet, "/", ""))
+	assert.Equal(1, recorder.writeHeaderCalls)
+	assert.Equal(http.StatusCreated, recorder.Code)
+	assert.Equal("written", recorder.Body.String())
+	if assert.Len(logger.entries, 2) {
+		assert.Equal(http.StatusCreated, logger.entries[1].Status)
+		assert.Equal(len("written"), logger.entries[1].Size)
+	}
+}
+
+func TestServeHTTPWrittenResponseNotAcceptable(t *testing.T) {
+	assert := assert.New(t)
+	controller := &writingController{TestController: NewTestController("Writing")}
+	server := CreateRESTServer(":8080", controller)
+	logger := &recordingAccessLogger{}
+	server.AccessLogger = logger
+
+	recorder := serve(server, http.MethodGet, "/", acceptHeader, "image/png")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("written", recorder.Body.String())
+	if assert.Len(logger.entries, 1) {
+		assert.Equal(http.StatusOK, logger.entries[0].Status)
+		assert.Empty(logger.entries[0].ErrorCode)
+	}
+}
+
+func TestServeHTTPErrorAfterWrite(t *testing.T) {
+	assert := assert.New(t)
+	controller := &writingController{TestController: NewTestController("Writing"), err: true}
+	server := CreateRESTServer(":8080", controller)
+	logger := &recordingAccessLogger{}
+	server.AccessLogger = logger
+
+	recorder := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	server.ServeHTTP(recorder, newRequest(http.MethodGet, "/", ""))
+	assert.Equal(1, recorder.writeHeaderCalls)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("written", recorder.Body.String())
+	if assert.Len(logger.entries, 1) {
+		assert.Equal(http.StatusOK, logger.entries[0].Status)
+		assert.Equal(qerror.SystemError, logger.entries[0].ErrorCode)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Kasita-Inc/gadget/stringutil"
@@ -13,6 +14,9 @@ var (
 	Slash    = "/"
 )
 
+// catchAllKey is the SubRoutes key for catch-all parameters.
+const catchAllKey = "**"
+
 // Router is the main entry point for the Quimby ReST API server.
 type Router struct {
 	// Routes All routes currently mapped by the router.
@@ -30,12 +34,27 @@ type RouteNode struct {
 	TemplateRoute string
 	// The Controller for this node if terminal.
 	Controller Controller
+	// The constraint on the URI parameter matched by this node, such as 'int'
+	// for '{{id:int}}', empty for unconstrained parameters and static words.
+	Constraint string
+
+	parameter bool
+	catchAll  bool
+	match     ParameterConstraint
+	// parameters are the parameter nodes in SubRoutes in the order they are
+	// tried, see precedence.
+	parameters []*RouteNode
 }
 
 func createNode(value string) *RouteNode {
 	return &RouteNode{Value: value, SubRoutes: make(map[string]*RouteNode)}
 }
 
+// matches checks if the passed path segment satisfies this parameter node.
+func (node *RouteNode) matches(segment string) bool {
+	return nil == node.match || node.match(segment)
+}
+
 // CreateRouter initializes and returns a new instance of Router.
 func CreateRouter(rootController Controller) Router {
 	router := Router{}
@@ -58,10 +77,31 @@ func (router *Router) AddController(controller Controller) error {
 	return err
 }
 
-func (node *RouteNode) insertRoute(route []string) *RouteNode {
+func (node *RouteNode) insertRoute(route []string) (*RouteNode, error) {
 	pathPart := route[0]
+	isParameter := WildCard == pathPart
+	parameter := routeParameter{}
 	if strings.HasPrefix(pathPart, stringutil.DOpen) {
+		isParameter = true
+		// malformed parameter names are treated as unconstrained and fail
+		// when the route is detemplated
+		p, err := parseParameter(pathPart)
+		if err == nil {
+			parameter = p
+		} else if strings.Contains(pathPart, constraintOperator) || strings.Contains(pathPart, catchAllSuffix) {
+			return nil, err
+		}
+	}
+	if isParameter {
 		pathPart = WildCard
+		if parameter.catchAll {
+			if len(route) > 1 {
+				return nil, fmt.Errorf("catch-all route parameter '%s' must be the last segment", route[0])
+			}
+			pathPart = catchAllKey
+		} else if "" != parameter.constraint {
+			pathPart = WildCard + constraintOperator + parameter.constraint
+		}
 	}
 
 	// check if the root of the route is in the SubRoutes
@@ -69,6 +109,12 @@ func (node *RouteNode) insertRoute(route []string) *RouteNode {
 	if !ok {
 		// no node yet so create a new one
 		v = createNode(pathPart)
+		if isParameter {
+			if err := v.setParameter(parameter); err != nil {
+				return nil, err
+			}
+			node.addParameter(v)
+		}
 		node.SubRoutes[v.Value] = v
 	}
 
@@ -77,7 +123,40 @@ func (node *RouteNode) insertRoute(route []string) *RouteNode {
 		return v.insertRoute(route[1:])
 	}
 
-	return v
+	return v, nil
+}
+
+func (node *RouteNode) setParameter(parameter routeParameter) error {
+	node.parameter = true
+	node.catchAll = parameter.catchAll
+	node.Constraint = parameter.constraint
+	if "" == parameter.constraint {
+		return nil
+	}
+	match, err := compileConstraint(parameter.constraint)
+	node.match = match
+	return err
+}
+
+// precedence orders parameter nodes: constrained parameters, then the
+// unconstrained wildcard, then the catch-all.
+func (node *RouteNode) precedence() int {
+	switch {
+	case node.catchAll:
+		return 2
+	case nil == node.match:
+		return 1
+	}
+	return 0
+}
+
+// addParameter adds a parameter sub node, keeping the parameters ordered by
+// precedence and then the order they were added.
+func (node *RouteNode) addParameter(parameter *RouteNode) {
+	node.parameters = append(node.parameters, parameter)
+	sort.SliceStable(node.parameters, func(i, j int) bool {
+		return node.parameters[i].precedence() < node.parameters[j].precedence()
+	})
 }
 
 // AddRoute adds a Controller at the specified route. This will not add
@@ -94,7 +173,10 @@ func (router *Router) AddRoute(route string, controller Controller) error {
 		return fmt.Errorf("Invalid route format '%s'. Remove leading, "+
 			"trailing, and double slashes", route)
 	}
-	node = router.RouteTree.insertRoute(splitRoute)
+	node, err = router.RouteTree.insertRoute(splitRoute)
+	if err != nil {
+		return err
+	}
 	if node.Controller == nil {
 		node.Controller = controller
 		node.TemplateRoute = route
@@ -106,23 +188,35 @@ func (router *Router) AddRoute(route string, controller Controller) error {
 	return err
 }
 
+// find locates the terminal node matching the passed path. At each level the
+// static sub route is tried first, then constrained parameters in the order
+// they were added, then the unconstrained wildcard, and finally a catch-all
+// which matches the remainder of the path. If a branch fails to match the rest
+// of the path the next candidate is tried.
 func (node *RouteNode) find(path []string) *RouteNode {
-	var foundNode *RouteNode
-	if len(path) > 0 {
-		subnode, ok := node.SubRoutes[path[0]]
-		// if there is no sub route below this node that matches the head
-		// of the slice, check for wildcard
-		if !ok {
-			subnode, ok = node.SubRoutes[WildCard]
+	if len(path) == 0 {
+		if node.Controller == nil {
+			return nil
 		}
-		// if we found either subnode, call find on it
-		if ok {
-			foundNode = subnode.find(path[1:])
+		return node
+	}
+	if subnode, ok := node.SubRoutes[path[0]]; ok && !subnode.parameter {
+		if foundNode := subnode.find(path[1:]); foundNode != nil {
+			return foundNode
+		}
+	}
+	for _, parameter := range node.parameters {
+		if !parameter.matches(path[0]) {
+			continue
+		}
+		if parameter.catchAll {
+			return parameter.find(nil)
+		}
+		if foundNode := parameter.find(path[1:]); foundNode != nil {
+			return foundNode
 		}
-	} else {
-		foundNode = node
 	}
-	return foundNode
+	return nil
 }
 
 // FindRouteForPath returns the controller that is currently assigned to
@@ -148,3 +242,33 @@ func (router *Router) FindRouteForPath(path string) (*RouteNode, error) {
 	}
 	return node, err
 }
+
+// detemplate extracts the URI parameters from the passed path using the
+// route template it was matched to.
+func detemplate(template string, path string) (map[string]string, error) {
+	templateParts := strings.Split(template, Slash)
+	pathParts := strings.Split(path, Slash)
+	mismatch := fmt.Errorf("path '%s' does not match template '%s'", path, template)
+	if len(templateParts) > len(pathParts) {
+		return nil, mismatch
+	}
+	parameters := make(map[string]string)
+	for i, part := range templateParts {
+		if !strings.HasPrefix(part, stringutil.DOpen) {
+			continue
+		}
+		parameter, err := parseParameter(part)
+		if err != nil {
+			return nil, err
+		}
+		if parameter.catchAll && i == len(templateParts)-1 {
+			parameters[parameter.name] = strings.Join(pathParts[i:], Slash)
+			return parameters, nil
+		}
+		parameters[parameter.name] = pathParts[i]
+	}
+	if len(templateParts) != len(pathParts) {
+		return nil, mismatch
+	}
+	return parameters, nil
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -342,3 +343,164 @@ func TestFindControllerNoRoutePartialMatch(t *testing.T) {
 			node.Value)
 	}
 }
+
+func TestFindControllerConstrainedParameters(t *testing.T) {
+	r := CreateRouter(nil)
+	routes := map[string]string{
+		"orders/{{id:int}}":                "int",
+		"orders/{{id:uuid}}":               "uuid",
+		"orders/{{name}}":                  "wild",
+		"orders/{{slug:regex([a-z-]+)}}":   "regex",
+		"orders/{{id:int}}/items":          "int items",
+		"orders/{{name}}/items/{{id:int}}": "wild items",
+	}
+	for route, id := range routes {
+		if err := r.AddRoute(route, &TestController{ID: id}); err != nil {
+			t.Error(err)
+		}
+	}
+	testRoute(r, "orders/42", "int", t)
+	testRoute(r, "orders/-42", "int", t)
+	testRoute(r, "orders/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "uuid", t)
+	testRoute(r, "orders/new-order", "regex", t)
+	testRoute(r, "orders/New_Order", "wild", t)
+	testRoute(r, "orders/42/items", "int items", t)
+	testRoute(r, "orders/ABC/items/7", "wild items", t)
+	if _, err := r.FindRouteForPath("orders/abc/items/seven"); err == nil {
+		t.Error("Expected no route for path with invalid int parameter.")
+	}
+}
+
+func TestFindControllerConstrainedParameterStaticPreferred(t *testing.T) {
+	r := CreateRouter(nil)
+	r.AddRoute("orders/{{id:int}}", &TestController{ID: "int"})
+	r.AddRoute("orders/42", &TestController{ID: "static"})
+	testRoute(r, "orders/42", "static", t)
+	testRoute(r, "orders/43", "int", t)
+	if _, err := r.FindRouteForPath("orders/*:int"); err == nil {
+		t.Error("Parameter nodes should not match by their key.")
+	}
+}
+
+func TestAddRouteInvalidConstraint(t *testing.T) {
+	r := CreateRouter(nil)
+	controller := &TestController{ID: "foo"}
+	if err := r.AddRoute("orders/{{id:unknown}}", controller); err == nil {
+		t.Error("Unknown constraint should fail.")
+	}
+	if err := r.AddRoute("orders/{{id:regex([a-z)}}", controller); err == nil {
+		t.Error("Invalid regex should fail.")
+	}
+}
+
+func TestRegisterParameterConstraint(t *testing.T) {
+	RegisterParameterConstraint("even", func(segment string) bool {
+		return strings.HasSuffix(segment, "0") || strings.HasSuffix(segment, "2")
+	})
+	r := CreateRouter(nil)
+	r.AddRoute("numbers/{{n:even}}", &TestController{ID: "even"})
+	r.AddRoute("numbers/{{n}}", &TestController{ID: "odd"})
+	testRoute(r, "numbers/12", "even", t)
+	testRoute(r, "numbers/13", "odd", t)
+}
+
+func TestDetemplate(t *testing.T) {
+	parameters, err := detemplate("orders/{{id:int}}/items/{{slug:regex([a-z]{2})}}", "orders/1/items/ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert.StringValueIn("id", "1", parameters, t)
+	Assert.StringValueIn("slug", "ab", parameters, t)
+
+	if _, err = detemplate("orders/{{id}}{{id2}}", "orders/1"); err == nil {
+		t.Error("Malformed template should fail.")
+	}
+	if _, err = detemplate("orders/{{id}}", "orders/1/2"); err == nil {
+		t.Error("Mismatched path should fail.")
+	}
+}
+
+func TestFindControllerBacktracksToWildcard(t *testing.T) {
+	r := CreateRouter(nil)
+	r.AddRoute("users/me/settings", &TestController{ID: "settings"})
+	r.AddRoute("users/{{id}}/orders", &TestController{ID: "orders"})
+	testRoute(r, "users/me/settings", "settings", t)
+	testRoute(r, "users/me/orders", "orders", t)
+	testRoute(r, "users/42/orders", "orders", t)
+	if _, err := r.FindRouteForPath("users/42/settings"); err == nil {
+		t.Error("Expected no route for path error.")
+	}
+}
+
+func TestFindControllerBacktracksFromNonTerminal(t *testing.T) {
+	r := CreateRouter(nil)
+	r.AddRoute("users/me/settings", &TestController{ID: "settings"})
+	r.AddRoute("users/{{id}}", &TestController{ID: "user"})
+	testRoute(r, "users/me", "user", t)
+}
+
+func TestFindControllerBacktracksBetweenConstraints(t *testing.T) {
+	r := CreateRouter(nil)
+	r.AddRoute("orders/{{slug:regex([a-z-]+)}}", &TestController{ID: "regex"})
+	r.AddRoute("orders/{{id:int}}/items", &TestController{ID: "int items"})
+	r.AddRoute("orders/{{name}}/items/{{id:int}}", &TestController{ID: "wild items"})
+	r.AddRoute("orders/{{name}}/items/{{id}}", &TestController{ID: "wild wild items"})
+	testRoute(r, "orders/abc", "regex", t)
+	testRoute(r, "orders/abc/items/7", "wild items", t)
+	testRoute(r, "orders/abc/items/seven", "wild wild items", t)
+	testRoute(r, "orders/42/items", "int items", t)
+	testRoute(r, "orders/42/items/7", "wild items", t)
+}
+
+func TestFindControllerBacktrackingPriority(t *testing.T) {
+	r := CreateRouter(nil)
+	// the earliest segment decides priority: a static match on the first
+	// segment wins over a wildcard even if the wildcard branch is more static
+	r.AddRoute("a/{{x}}/c", &TestController{ID: "static first"})
+	r.AddRoute("{{y}}/b/c", &TestController{ID: "wild first"})
+	testRoute(r, "a/b/c", "static first", t)
+	testRoute(r, "z/b/c", "wild first", t)
+}
+
+func TestFindControllerCatchAll(t *testing.T) {
+	r := CreateRouter(nil)
+	r.AddRoute("files/{{path...}}", &TestController{ID: "catch all"})
+	r.AddRoute("files/{{id}}", &TestController{ID: "wild"})
+	r.AddRoute("files/{{id:int}}", &TestController{ID: "int"})
+	r.AddRoute("files/readme", &TestController{ID: "static"})
+	r.AddRoute("files/{{id}}/meta", &TestController{ID: "meta"})
+	testRoute(r, "files/readme", "static", t)
+	testRoute(r, "files/42", "int", t)
+	testRoute(r, "files/docs", "wild", t)
+	testRoute(r, "files/docs/meta", "meta", t)
+	testRoute(r, "files/docs/guide/intro.md", "catch all", t)
+	testRoute(r, "files/readme/meta", "meta", t)
+	testRoute(r, "files/readme/other", "catch all", t)
+	if _, err := r.FindRouteForPath("files"); err == nil {
+		t.Error("Catch-all should require at least one segment.")
+	}
+}
+
+func TestAddRouteCatchAllNotLast(t *testing.T) {
+	r := CreateRouter(nil)
+	controller := &TestController{ID: "foo"}
+	if err := r.AddRoute("files/{{path...}}/meta", controller); err == nil {
+		t.Error("Catch-all before the last segment should fail.")
+	}
+	if err := r.AddRoute("files/{{path...:int}}", controller); err == nil {
+		t.Error("Constrained catch-all should fail.")
+	}
+}
+
+func TestDetemplateCatchAll(t *testing.T) {
+	parameters, err := detemplate("proxy/{{host}}/{{path...}}", "proxy/example.com/a/b/c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert.StringValueIn("host", "example.com", parameters, t)
+	Assert.StringValueIn("path", "a/b/c.txt", parameters, t)
+
+	if _, err = detemplate("proxy/{{host}}/{{path...}}", "proxy/example.com"); err == nil {
+		t.Error("Missing catch-all segment should fail.")
+	}
+}
@@ -1,15 +1,42 @@
 package http
 
 import (
-	"encoding/json"
+	"bytes"
+	stdcontext "context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Kasita-Inc/gadget/errors"
+	"github.com/Kasita-Inc/gadget/generator"
 	"github.com/Kasita-Inc/gadget/log"
 	qerror "github.com/Kasita-Inc/quimby/error"
 )
 
-const healthCheckURI = "/" + HealthCheckRoute
+// DefaultRequestIDHeader is the header used to correlate requests when no
+// header is set on the RESTServer.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDPrefix    = "req"
+	maxRequestIDLength = 128
+)
+
+const (
+	// DefaultReadTimeout is the deadline for reading a request, including its
+	// body, when no ReadTimeout is set on the RESTServer.
+	DefaultReadTimeout = 5 * time.Second
+	// DefaultWriteTimeout is the time allowed for writing a response after
+	// the request timeout when no WriteTimeout is set on the RESTServer.
+	DefaultWriteTimeout = 10 * time.Second
+)
 
 // RESTServer is a struct for managing the configuration and start up of a
 // http/s server using the routing and controller logic in this package.
@@ -17,118 +44,598 @@ type RESTServer struct {
 	Address string
 	Port    int
 	Router  Router
+
+	// CertificateFile and KeyFile are the PEM encoded certificate / key pair
+	// used by ListenAndServeTLS.
+	CertificateFile string
+	KeyFile         string
+	// CertificateReloadInterval is how often the certificate / key pair is
+	// checked for changes on disk, defaults to DefaultCertificateReloadInterval.
+	CertificateReloadInterval time.Duration
+	// ConfigureTLS is called with the tls.Config before the https server
+	// starts, allowing cipher suite and minimum version policy to be set.
+	ConfigureTLS func(config *tls.Config)
+
+	// DrainDelay is how long Shutdown reports the server as draining through
+	// the health check before it stops accepting new connections, giving load
+	// balancers time to stop routing to it.
+	DrainDelay time.Duration
+
+	// RequestTimeout is the deadline for handling a request, after which the
+	// Context is cancelled and a request-timeout error returned. Controllers
+	// run to completion, so the error is only returned once the controller
+	// returns; controllers doing slow work should stop when Context.Done is
+	// closed and pass the Context to the calls they make. Zero means no
+	// timeout, see also SetRouteTimeout.
+	RequestTimeout time.Duration
+
+	// ReadTimeout is the deadline for reading a request, including its body,
+	// defaults to DefaultReadTimeout. Servers accepting large or slow uploads
+	// should increase it, a negative value disables it.
+	ReadTimeout time.Duration
+	// WriteTimeout is the deadline for writing the response, measured from
+	// when the request headers are read. It defaults to the longest
+	// RequestTimeout of the server and its routes plus DefaultWriteTimeout,
+	// so that a request-timeout error can still be returned. A negative value
+	// disables it.
+	WriteTimeout time.Duration
+
+	// RequestIDHeader is the header the request ID is read from and returned
+	// in, defaults to DefaultRequestIDHeader.
+	RequestIDHeader string
+
+	// MaxBodySize is the size in bytes of the largest request body accepted,
+	// larger bodies fail with a request-too-large error. Zero accepts bodies
+	// of any size.
+	MaxBodySize int64
+	// MultipartLimits configures the memory and size limits for reading
+	// multipart/form-data bodies, see Context.ReadMultipart.
+	MultipartLimits *MultipartLimits
+
+	// AccessLogger records each request, defaults to a JSONAccessLogger
+	// writing to the access log.
+	AccessLogger AccessLogger
+	// AccessLogRedaction is applied to access log entries, defaults to
+	// DefaultRedactionPolicy.
+	AccessLogRedaction *RedactionPolicy
+	// AccessLogSampleRate is the fraction of successful requests logged,
+	// between 0 and 1. Zero logs every request. Requests failing with a
+	// client or server error are always logged.
+	AccessLogSampleRate float64
+
+	// Metrics records the requests handled by the server when set, see
+	// NewMetrics and controllers.MetricsController.
+	Metrics *Metrics
+
+	// Tracer starts a span for each request when set, see Context.Span.
+	Tracer *Tracer
+
+	// ProblemDetails renders errors as RFC 7807 problem details for every
+	// request. Otherwise problem details are rendered when the Accept header
+	// prefers application/problem+json.
+	ProblemDetails bool
+
+	// Debug includes the panic value and stack trace in the response when a
+	// controller panics.
+	Debug bool
+
+	middleware       []Middleware
+	prefixMiddleware []prefixMiddleware
+	corsPolicies     []prefixCORSPolicy
+	routeTimeouts    map[string]time.Duration
+
+	accessLogExclusions [][]string
+
+	mutex       sync.Mutex
+	httpServers []*http.Server
+	draining    bool
+	shutdown    bool
 }
 
-// CreateRESTServer initializes a RESTServer struct and returns it.
-func CreateRESTServer(address string, rootController Controller) RESTServer {
-	server := RESTServer{Address: address}
+// CreateRESTServer initializes a RESTServer struct and returns a pointer to
+// it.
+func CreateRESTServer(address string, rootController Controller) *RESTServer {
+	server := &RESTServer{Address: address}
 	server.Router = CreateRouter(rootController)
+	server.ExcludeFromAccessLog(HealthCheckRoute)
 	return server
 }
 
 // ServeHTTP processes the HTTP Request
 func (server *RESTServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	context := CreateContext(w, r, server.Router)
-	if !context.HasError() {
-		switch context.Request.Method {
-		case http.MethodGet:
-			context.Route.Controller.Get(context)
-		case http.MethodPost:
-			context.Route.Controller.Post(context)
-		case http.MethodPut:
-			context.Route.Controller.Put(context)
-		case http.MethodPatch:
-			context.Route.Controller.Patch(context)
-		case http.MethodDelete:
-			context.Route.Controller.Delete(context)
-		case http.MethodOptions:
-			context.Route.Controller.Options(context)
-		default:
-			context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
-		}
+	context.server = server
+	server.setRequestID(context)
+	server.limitBody(context)
+	ctx, cancel := server.requestContext(context)
+	defer cancel()
+	context.ctx = ctx
+	if nil != server.Tracer {
+		server.startRequestSpan(context)
+		defer finishRequestSpan(context)
+	}
+	if nil != server.Metrics {
+		server.Metrics.started()
+		defer func() { server.Metrics.completed(context, time.Since(context.start)) }()
 	}
+	defer context.responseWriter().complete()
+	defer context.removeUploads()
+	policy := server.corsPolicyFor(context)
+	if nil != policy {
+		policy.decorate(context)
+	}
+	// preflight requests are answered before the middleware, which browsers
+	// do not send credentials to
+	if !server.preflight(context, policy) {
+		handler := chain(server.dispatch, server.middlewareFor(context))
+		server.handle(context, func(context *Context) {
+			server.authenticate(context)
+			handler(context)
+		})
+	}
+	if stdcontext.DeadlineExceeded == context.Err() {
+		context.Fail(qerror.RequestTimeout)
+	}
+	server.setAllowHeader(context)
 	server.CompleteRequest(context)
 }
 
+// setRequestID uses the request ID sent by the client if it is valid,
+// otherwise generates one, and echoes it in the response.
+func (server *RESTServer) setRequestID(context *Context) {
+	header := server.RequestIDHeader
+	if "" == header {
+		header = DefaultRequestIDHeader
+	}
+	context.RequestID = context.Request.Header.Get(header)
+	if !validRequestID(context.RequestID) {
+		context.RequestID = generator.ID(requestIDPrefix)
+	}
+	context.Response.Header().Set(header, context.RequestID)
+}
+
+// validRequestID checks that a client supplied request ID is a reasonable
+// length and only contains visible ASCII characters.
+func validRequestID(id string) bool {
+	if 0 == len(id) || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// SetRouteTimeout sets the request timeout for the passed route template,
+// overriding the RequestTimeout on the server. Zero disables the timeout.
+func (server *RESTServer) SetRouteTimeout(route string, timeout time.Duration) {
+	if nil == server.routeTimeouts {
+		server.routeTimeouts = make(map[string]time.Duration)
+	}
+	server.routeTimeouts[route] = timeout
+}
+
+// requestContext creates the context.Context for the request, applying the
+// timeout for the route.
+func (server *RESTServer) requestContext(context *Context) (stdcontext.Context, stdcontext.CancelFunc) {
+	timeout := server.RequestTimeout
+	if nil != context.Route {
+		if routeTimeout, ok := server.routeTimeouts[context.Route.TemplateRoute]; ok {
+			timeout = routeTimeout
+		}
+	}
+	if timeout > 0 {
+		return stdcontext.WithTimeout(context.Request.Context(), timeout)
+	}
+	return stdcontext.WithCancel(context.Request.Context())
+}
+
+// setAllowHeader reports the methods supported by the routed Controller on
+// method not allowed responses, the same methods returned for OPTIONS. The
+// request method is excluded as the Controller has rejected it, along with
+// HEAD or GET as both are handled by Get.
+func (server *RESTServer) setAllowHeader(context *Context) {
+	if http.StatusMethodNotAllowed != context.Status() || nil == context.Route {
+		return
+	}
+	rejected := []string{context.Request.Method}
+	if http.MethodGet == context.Request.Method || http.MethodHead == context.Request.Method {
+		rejected = []string{http.MethodGet, http.MethodHead}
+	}
+	allowed := []string{}
+	for _, method := range AllowedMethods(context.Route.Controller) {
+		if !containsMethod(rejected, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	context.Response.Header().Set(allowHeader, strings.Join(allowed, ", "))
+}
+
+// handle runs the passed handler, converting a panic into a system-error.
+func (server *RESTServer) handle(context *Context, handler Handler) {
+	defer server.recoverPanic(context)
+	handler(context)
+}
+
+func (server *RESTServer) recoverPanic(context *Context) {
+	recovered := recover()
+	if nil == recovered {
+		return
+	}
+	// allow handlers to abort the response the same as net/http
+	if http.ErrAbortHandler == recovered {
+		panic(recovered)
+	}
+	trace := errors.GetStackTrace()
+	log.Errorf("panic handling %s %s: %v\n%s", context.Method, context.URI, recovered, strings.Join(trace, "\n"))
+
+	context.Fail(qerror.SystemError)
+	if server.Debug {
+		context.Error.Message = fmt.Sprintf("%v", recovered)
+		for _, line := range trace {
+			context.Error.AddDetail(line)
+		}
+	}
+	// the error is always rendered as JSON regardless of what the controller set
+	context.Response.Header().Del(contentTypeHeader)
+}
+
+// authenticate calls Authenticate on the routed Controller, failing the
+// request if it is not authenticated. OPTIONS requests are not
+// authenticated.
+func (server *RESTServer) authenticate(context *Context) {
+	if context.HasError() || nil == context.Route || http.MethodOptions == context.Request.Method {
+		return
+	}
+	if !context.Route.Controller.Authenticate(context) {
+		context.Fail(qerror.AuthenticationFailed)
+	}
+}
+
+// dispatch calls the method on the routed Controller matching the request
+// method, unless an error has already been set on the Context. Methods the
+// Controller has previously rejected are still dispatched to it.
+func (server *RESTServer) dispatch(context *Context) {
+	if context.HasError() {
+		return
+	}
+	if !containsMethod(allowedMethods(context.Route.Controller, nil), context.Request.Method) {
+		context.Fail(qerror.MethodNotAllowed)
+		return
+	}
+	defer func() {
+		if context.HasError() && qerror.MethodNotAllowed == context.Error.Code &&
+			http.MethodOptions != context.Request.Method {
+			rejectMethod(context.Route.Controller, context.Request.Method)
+		}
+	}()
+	switch context.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		context.Route.Controller.Get(context)
+	case http.MethodPost:
+		context.Route.Controller.Post(context)
+	case http.MethodPut:
+		context.Route.Controller.Put(context)
+	case http.MethodPatch:
+		context.Route.Controller.Patch(context)
+	case http.MethodDelete:
+		context.Route.Controller.Delete(context)
+	case http.MethodOptions:
+		server.options(context)
+	default:
+		context.Fail(qerror.MethodNotAllowed)
+	}
+}
+
+// preflight answers a CORS preflight request for a routed Controller using
+// the passed CORS policy, returning false if the request is not an allowed
+// preflight.
+func (server *RESTServer) preflight(context *Context, policy *CORSPolicy) bool {
+	if nil == policy || context.HasError() || nil == context.Route || nil == context.Route.Controller ||
+		!isPreflight(context.Request) || !policy.allowsOrigin(context.Request.Header.Get(originHeader)) {
+		return false
+	}
+	return policy.preflight(context, AllowedMethods(context.Route.Controller))
+}
+
+// options calls Options on the routed Controller if it implements it,
+// otherwise the allowed methods are returned in the Allow header. CORS
+// preflight requests have already been answered by ServeHTTP.
+func (server *RESTServer) options(context *Context) {
+	controller := context.Route.Controller
+	declared, ok := controller.(AllowedMethodsController)
+	if !ok || containsMethod(declared.AllowedMethods(), http.MethodOptions) {
+		controller.Options(context)
+		if !context.HasError() || qerror.MethodNotAllowed != context.Error.Code {
+			return
+		}
+		context.Error = nil
+	}
+	context.Response.Header().Set(allowHeader, strings.Join(AllowedMethods(controller), ", "))
+	context.SetResponse(nil, http.StatusNoContent)
+}
+
 const (
 	contentTypeHeader = "Content-Type"
+	allowHeader       = "Allow"
 	contentTypeJSON   = "application/json"
 	contentTypeForm   = "application/x-www-form-urlencoded"
 )
 
-// CompleteRequest generates output and completes the Request
+// CompleteRequest encodes the Model, or the Error, and completes the
+// Request. The response is encoded in the media type most preferred by the
+// request Accept header, see RegisterEncoder, falling back to less preferred
+// acceptable media types if its encoder cannot encode the model. A Model
+// none of the acceptable media types can encode fails with not-acceptable,
+// errors fall back to JSON. Controllers may override the media type by
+// setting the Content-Type header, a string or []byte Model is then written
+// as is.
 func (server *RESTServer) CompleteRequest(context *Context) {
-	if "" == context.Response.Header().Get(contentTypeHeader) { // if not set assuming it's JSON
-		server.completeRequestJSON(context)
-		return
+	if context.HasError() && "" == context.Error.RequestID {
+		context.Error.RequestID = context.RequestID
 	}
-
-	if healthCheckURI != context.URI {
-		log.Accessf("%s %s %s %s %#v %d %s %s",
-			context.Request.RemoteAddr,
-			context.Request.Method, context.Request.URL.String(), context.Request.Proto, context.URLParameters,
-			context.Status(),
-			context.Request.UserAgent(), context.Request.Referer())
+	writer := context.responseWriter()
+	if writer.Written() && context.HasError() {
+		// the controller already wrote to the response so the error cannot
+		// be returned to the client
+		log.Errorf("%s %s: dropped error '%s' as the response was already written",
+			context.Method, context.URI, context.Error.Code)
+		server.logAccess(context)
+		return
 	}
 
-	b := []byte{}
+	var model interface{} = context.Model
 	if context.HasError() {
-		b = []byte(context.Error.Message)
-	} else if nil != context.Model {
-		b = []byte(context.Model.(string))
+		model = context.Error
+	}
+	header := context.Response.Header()
+	contentType := header.Get(contentTypeHeader)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	encoder, ok := encoderFor(mediaType)
+	// mediaTypes are the negotiated media types to encode the model in, in
+	// order of preference
+	var mediaTypes []string
+	if "" == contentType && context.HasError() && server.rendersProblem(context.Request.Header.Get(acceptHeader)) {
+		header.Add(varyHeader, acceptHeader)
+		model = qerror.NewProblem(context.Error, context.Status(), context.Request.URL.Path)
+		contentType, encoder = qerror.ProblemMediaType, encodeJSON
+	} else if "" == contentType && writer.Written() {
+		// the controller streamed the response without a Content-Type so it
+		// is too late to negotiate one
+		encoder = encodeJSON
+	} else if "" == contentType {
+		header.Add(varyHeader, acceptHeader)
+		if mediaTypes = negotiateEncoders(context.Request.Header.Get(acceptHeader)); 0 == len(mediaTypes) {
+			context.Fail(qerror.NotAcceptable)
+			context.Error.RequestID = context.RequestID
+			model = context.Error
+			mediaTypes = []string{MediaTypeJSON}
+		} else if context.HasError() {
+			mediaTypes = append(mediaTypes, MediaTypeJSON)
+		}
+	} else if !ok || isRawModel(model) {
+		encoder = encodeRaw
 	}
 
-	context.Response.WriteHeader(context.responseStatus)
-	context.Response.Write(b)
+	b := &bytes.Buffer{}
+	if nil != model || !writer.Written() {
+		var err error
+		if len(mediaTypes) > 0 {
+			if contentType, err = encodeNegotiated(b, model, mediaTypes); nil == err && "" == contentType {
+				log.Errorf("%s %s: no acceptable media type can encode %T", context.Method, context.URI, model)
+				context.Fail(qerror.NotAcceptable)
+				context.Error.RequestID = context.RequestID
+				contentType = MediaTypeJSON
+				encodeJSON(b, context.Error)
+			}
+		} else {
+			err = encoder(b, model)
+		}
+		if err != nil && writer.Written() {
+			log.Errorf("%s %s: dropped model that failed to encode as '%s' as the response was already written: %s",
+				context.Method, context.URI, contentType, err)
+			b.Reset()
+		} else if err != nil {
+			log.Errorf("%s %s: failed to encode response as '%s': %s", context.Method, context.URI, contentType, err)
+			context.Fail(qerror.SystemError)
+			context.Error.RequestID = context.RequestID
+			contentType = MediaTypeJSON
+			b.Reset()
+			encodeJSON(b, context.Error)
+		}
+	}
+	if bodyAllowed(context.responseStatus) && !writer.Written() {
+		header.Set(contentTypeHeader, contentType)
+	}
+	writeResponse(context, b.Bytes())
+	server.logAccess(context)
 }
 
-func (server *RESTServer) completeRequestJSON(context *Context) {
-	context.Response.Header().Add(contentTypeHeader, contentTypeJSON)
-	var b []byte
-	var e error
-	if context.HasError() {
-		b, e = json.Marshal(context.Error)
-		if e != nil {
-			context.SetError(qerror.NewRestError("system-error", "", nil), http.StatusInternalServerError)
-			b, _ = json.Marshal(context.Error)
+// encodeNegotiated encodes the model in the first of the media types with an
+// encoder that can encode it and returns the media type it was encoded in,
+// or an empty media type if none can. The error encoding JSON is returned as
+// any model that can be encoded can be encoded as JSON.
+func encodeNegotiated(b *bytes.Buffer, model interface{}, mediaTypes []string) (string, error) {
+	for _, mediaType := range mediaTypes {
+		encoder, ok := encoderFor(mediaType)
+		if !ok {
+			continue
 		}
-	} else {
-		b, e = json.Marshal(context.Model)
-		if e != nil {
-			context.SetError(qerror.NewRestError("system-error", "", nil), http.StatusInternalServerError)
-			b, _ = json.Marshal(context.Error)
+		b.Reset()
+		if err := encoder(b, model); nil == err || MediaTypeJSON == mediaType {
+			return mediaType, err
 		}
 	}
+	b.Reset()
+	return "", nil
+}
 
-	if healthCheckURI != context.URI {
-		log.Accessf("%s %s %s %s %#v %s %d %s %s",
-			context.Request.RemoteAddr,
-			context.Request.Method, context.Request.URL.String(), context.Request.Proto, context.URLParameters, context.Body,
-			context.Status(),
-			context.Request.UserAgent(), context.Request.Referer())
+// isRawModel checks if the model is a string or []byte, which are written as
+// is in the Content-Type set by the controller.
+func isRawModel(model interface{}) bool {
+	switch model.(type) {
+	case string, []byte:
+		return true
 	}
-	context.Response.WriteHeader(context.responseStatus)
-	context.Response.Write(b)
+	return false
+}
+
+// encodeRaw writes a string or []byte model as is, and the message of an
+// error, for media types without a registered encoder.
+func encodeRaw(w io.Writer, model interface{}) error {
+	switch model.(type) {
+	case nil, string, []byte, *qerror.RestError:
+		return encodeText(w, model)
+	}
+	return fmt.Errorf("no encoder registered for %T", model)
 }
 
 // ListenAndServe starts a http server listening on the address specified
-// on the RESTServer instance.
+// on the RESTServer instance. It blocks until the server is shut down.
 func (server *RESTServer) ListenAndServe() error {
+	listener, err := net.Listen("tcp", server.Address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// ListenAndServeTLS starts a https server listening on the passed address and
+// port, if neither are set the address on the RESTServer instance is used.
+// It blocks until the server is shut down.
+func (server *RESTServer) ListenAndServeTLS(address string, port int) error {
+	addr := server.Address
+	if "" != address || 0 != port {
+		addr = net.JoinHostPort(address, strconv.Itoa(port))
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return server.ServeTLS(listener)
+}
+
+// Serve accepts http connections on the passed listener until the server is
+// shut down. A graceful Shutdown causes Serve to return nil.
+func (server *RESTServer) Serve(listener net.Listener) error {
+	return server.serve(listener, nil)
+}
+
+// ServeTLS accepts https connections on the passed listener until the server
+// is shut down. The certificate is loaded from the CertificateFile and KeyFile
+// on the RESTServer and reloaded when they change, until ServeTLS returns.
+func (server *RESTServer) ServeTLS(listener net.Listener) error {
+	loader, err := NewCertificateLoader(server.CertificateFile, server.KeyFile)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go loader.Watch(server.CertificateReloadInterval, stop)
+	return server.serve(listener, server.createTLSConfig(loader))
+}
+
+func (server *RESTServer) serve(listener net.Listener, config *tls.Config) error {
 	srv := &http.Server{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		Addr:         server.Address,
+		ReadTimeout:  server.readTimeout(),
+		WriteTimeout: server.writeTimeout(),
+		Addr:         listener.Addr().String(),
 		Handler:      server,
+		TLSConfig:    config,
+	}
+	server.mutex.Lock()
+	if server.shutdown {
+		server.mutex.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
 	}
-	err := srv.ListenAndServe()
-	return log.Fatal(err)
+	server.httpServers = append(server.httpServers, srv)
+	server.mutex.Unlock()
+
+	var err error
+	if nil == config {
+		err = srv.Serve(listener)
+	} else {
+		err = srv.ServeTLS(listener, "", "")
+	}
+	if http.ErrServerClosed == err {
+		return nil
+	}
+	return err
 }
 
-// ListenAndServeTLS starts a https server listening on the address specified
-// on the RESTServer instance.
-func (server *RESTServer) ListenAndServeTLS(address string, port int) error {
-	// qserver := &QuimbyServer{Address: addr}
-	// err := http.ListenAndServeTLS(":10443", "cert.pem", "key.pem", nil)
-	// log.Fatal(err)
-	return nil
+// readTimeout returns the ReadTimeout of the server, or the default.
+func (server *RESTServer) readTimeout() time.Duration {
+	if 0 == server.ReadTimeout {
+		return DefaultReadTimeout
+	}
+	return server.ReadTimeout
+}
+
+// writeTimeout returns the WriteTimeout of the server, or the default derived
+// from the longest request timeout.
+func (server *RESTServer) writeTimeout() time.Duration {
+	if 0 != server.WriteTimeout {
+		return server.WriteTimeout
+	}
+	timeout := server.RequestTimeout
+	for _, routeTimeout := range server.routeTimeouts {
+		if routeTimeout > timeout {
+			timeout = routeTimeout
+		}
+	}
+	return timeout + DefaultWriteTimeout
+}
+
+func (server *RESTServer) createTLSConfig(loader *CertificateLoader) *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: loader.GetCertificate,
+	}
+	if nil != server.ConfigureTLS {
+		server.ConfigureTLS(config)
+	}
+	return config
+}
+
+// Draining returns true once Shutdown has been called on the server.
+func (server *RESTServer) Draining() bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.draining
+}
+
+// Shutdown gracefully stops the server. The health check reports the server as
+// draining for the DrainDelay, then listeners are closed and Shutdown waits for
+// in-flight requests to complete. If the passed context expires first its
+// error is returned and remaining connections are left to finish on their own.
+func (server *RESTServer) Shutdown(ctx stdcontext.Context) error {
+	server.mutex.Lock()
+	server.draining = true
+	server.mutex.Unlock()
+
+	if server.DrainDelay > 0 {
+		timer := time.NewTimer(server.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	server.mutex.Lock()
+	server.shutdown = true
+	httpServers := server.httpServers
+	server.mutex.Unlock()
+
+	var err error
+	for _, srv := range httpServers {
+		if e := srv.Shutdown(ctx); e != nil {
+			err = e
+		}
+	}
+	return err
 }
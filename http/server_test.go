@@ -1,12 +1,19 @@
 package http
 
 import (
+	stdcontext "context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"math"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -35,6 +42,179 @@ func (rw testResponseWriter) WriteHeader(i int) {
 	*rw.Status = i
 }
 
+type blockingController struct {
+	TestController
+	started chan bool
+	release chan bool
+}
+
+func newBlockingController() *blockingController {
+	return &blockingController{
+		TestController: NewTestController("Blocking"),
+		started:        make(chan bool, 1),
+		release:        make(chan bool),
+	}
+}
+
+func (controller *blockingController) Get(context *Context) {
+	controller.started <- true
+	<-controller.release
+	context.SetResponse("done", http.StatusOK)
+}
+
+// startServer begins serving the passed server on a random local port and
+// returns the base URL and a channel that receives the result of Serve.
+func startServer(t *testing.T, server *RESTServer) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	return "http://" + listener.Addr().String(), served
+}
+
+type panicController struct {
+	TestController
+}
+
+func (controller *panicController) Get(context *Context) {
+	context.Response.Header().Set(contentTypeHeader, "text/plain")
+	var model map[string]string
+	model["boom"] = "panic"
+}
+
+type panicAuthenticateController struct {
+	TestController
+}
+
+func (controller *panicAuthenticateController) Authenticate(context *Context) bool {
+	var credentials map[string]string
+	credentials["user"] = "panic"
+	return true
+}
+
+type declaredMethodsController struct {
+	TestController
+}
+
+func (controller *declaredMethodsController) AllowedMethods() []string {
+	return []string{http.MethodGet, http.MethodPut}
+}
+
+type rejectingPutController struct {
+	TestController
+}
+
+func (controller *rejectingPutController) Put(context *Context) {
+	controller.MethodCalled = http.MethodPut
+	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+}
+
+type rejectingGetController struct {
+	declaredMethodsController
+}
+
+func (controller *rejectingGetController) Get(context *Context) {
+	context.Fail(qerror.MethodNotAllowed)
+}
+
+type rejectingOptionsController struct {
+	TestController
+}
+
+func (controller *rejectingOptionsController) Options(context *Context) {
+	context.SetError(qerror.NewRestError(qerror.MethodNotAllowed, "", nil), http.StatusMethodNotAllowed)
+}
+
+type streamingController struct {
+	TestController
+}
+
+func (controller *streamingController) Get(context *Context) {
+	context.Response.Header().Set(contentTypeHeader, "text/plain")
+	context.Write("streamed ")
+	context.SetResponse("body", http.StatusOK)
+}
+
+// waitingController responds after a delay unless the request is cancelled.
+type waitingController struct {
+	TestController
+	err error
+}
+
+func (controller *waitingController) Get(context *Context) {
+	select {
+	case <-context.Done():
+		controller.err = context.Err()
+		context.SetError(qerror.NewRestError(qerror.SystemError, "", nil), http.StatusInternalServerError)
+	case <-time.After(250 * time.Millisecond):
+		context.SetResponse("done", http.StatusOK)
+	}
+}
+
+// newRequest creates a request to the target with the passed body, which is
+// not sent if empty, and header name and value pairs.
+func newRequest(method, target, body string, headers ...string) *http.Request {
+	var reader io.Reader
+	if "" != body {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, target, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	return request
+}
+
+// serveRequest sends the request to the server, returning the recorded
+// response.
+func serveRequest(server *RESTServer, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// serve sends a request without a body and with the passed header name and
+// value pairs to the server.
+func serve(server *RESTServer, method, target string, headers ...string) *httptest.ResponseRecorder {
+	return serveRequest(server, newRequest(method, target, "", headers...))
+}
+
+// createRequestContext creates a Context for the request as the passed
+// server would, the server may be nil.
+func createRequestContext(server *RESTServer, request *http.Request) *Context {
+	values, _ := url.ParseQuery(request.URL.RawQuery)
+	context := &Context{Request: request, URLParameters: values, server: server}
+	if nil != server {
+		server.limitBody(context)
+	}
+	return context
+}
+
+// fieldErrorCodes returns the code of each FieldError of a RestError or
+// FieldErrors keyed by field.
+func fieldErrorCodes(err error) map[string]string {
+	codes := map[string]string{}
+	switch e := err.(type) {
+	case *qerror.RestError:
+		if nil != e {
+			for _, detail := range e.Details {
+				if fieldError, ok := detail.(qerror.FieldError); ok {
+					codes[fieldError.Field] = fieldError.Code
+				}
+			}
+		}
+	case FieldErrors:
+		for _, fieldError := range e {
+			codes[fieldError.Field] = fieldError.Code
+		}
+	}
+	return codes
+}
+
 type assertions struct {
 }
 
@@ -181,3 +361,404 @@ func TestCompleteRequestResponseCannotMarshal(t *testing.T) {
 	assert.Equal(string(exp), stringutil.NullTerminatedString(writerBody))
 	assert.Equal(http.StatusInternalServerError, writerStatus)
 }
+
+func TestShutdownWaitsForInFlightRequests(t *testing.T) {
+	assert := assert.New(t)
+	controller := newBlockingController()
+	server := CreateRESTServer("127.0.0.1:0", controller)
+	baseURL, served := startServer(t, server)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		response, err := http.Get(baseURL + "/")
+		assert.NoError(err)
+		responses <- response
+	}()
+	<-controller.started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(stdcontext.Background())
+	}()
+	for !server.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the in-flight request completed.")
+	case <-time.After(250 * time.Millisecond):
+	}
+
+	close(controller.release)
+	response := <-responses
+	if assert.NotNil(response) {
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.Equal("\"done\"", string(body))
+	}
+	assert.NoError(<-shutdown)
+	assert.NoError(<-served)
+
+	// the server no longer accepts connections
+	_, err := http.Get(baseURL + "/")
+	assert.Error(err)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+	controller := newBlockingController()
+	server := CreateRESTServer("127.0.0.1:0", controller)
+	baseURL, served := startServer(t, server)
+	defer close(controller.release)
+
+	go http.Get(baseURL + "/")
+	<-controller.started
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(stdcontext.DeadlineExceeded, server.Shutdown(ctx))
+	assert.NoError(<-served)
+}
+
+func TestServeAfterShutdown(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer("127.0.0.1:0", &controller)
+	assert.NoError(server.Shutdown(stdcontext.Background()))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	assert.Equal(http.ErrServerClosed, server.Serve(listener))
+}
+
+func TestContextDraining(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+	server.DrainDelay = time.Hour
+
+	context := &Context{server: server}
+	assert.False(context.Draining())
+	assert.False((&Context{}).Draining())
+
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(ctx)
+	}()
+	for !server.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(context.Draining())
+	cancel()
+	assert.NoError(<-shutdown)
+}
+
+func TestServeHTTPRecoversPanic(t *testing.T) {
+	assert := assert.New(t)
+	controller := &panicController{TestController: NewTestController("Panic")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusInternalServerError, recorder.Code)
+	assert.Equal(contentTypeJSON, recorder.Header().Get(contentTypeHeader))
+	restError := &qerror.RestError{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), restError))
+	assert.Equal(qerror.SystemError, restError.Code)
+	assert.Empty(restError.Message)
+	assert.Empty(restError.Details)
+}
+
+func TestServeHTTPRecoversAuthenticatePanic(t *testing.T) {
+	assert := assert.New(t)
+	controller := &panicAuthenticateController{TestController: NewTestController("Panic")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusInternalServerError, recorder.Code)
+	assert.Empty(controller.MethodCalled)
+	assert.NotEmpty(recorder.Header().Get(DefaultRequestIDHeader))
+	restError := &qerror.RestError{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), restError))
+	assert.Equal(qerror.SystemError, restError.Code)
+	assert.Equal(recorder.Header().Get(DefaultRequestIDHeader), restError.RequestID)
+}
+
+func TestServeHTTPRecoversPanicDebug(t *testing.T) {
+	assert := assert.New(t)
+	controller := &panicController{TestController: NewTestController("Panic")}
+	server := CreateRESTServer(":8080", controller)
+	server.Debug = true
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusInternalServerError, recorder.Code)
+	restError := &qerror.RestError{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), restError))
+	assert.Equal(qerror.SystemError, restError.Code)
+	assert.Contains(restError.Message, "nil map")
+	assert.NotEmpty(restError.Details)
+}
+
+func TestServeHTTPAbortHandler(t *testing.T) {
+	controller := NewTestController("Abort")
+	server := CreateRESTServer(":8080", &controller)
+	server.Use(func(context *Context, next Handler) {
+		panic(http.ErrAbortHandler)
+	})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(server, http.MethodGet, "/")
+	})
+}
+
+func TestServeHTTPNotFound(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+
+	recorder := serve(server, http.MethodGet, "/does/not/exist")
+	assert.Equal(http.StatusNotFound, recorder.Code)
+	assert.Contains(recorder.Body.String(), qerror.NotFound)
+	assert.Empty(recorder.Header().Get(allowHeader))
+}
+
+func TestServeHTTPMethodNotAllowedDeclared(t *testing.T) {
+	assert := assert.New(t)
+	controller := &declaredMethodsController{TestController: NewTestController("Declared")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodPost, "/")
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal("GET, HEAD, PUT, OPTIONS", recorder.Header().Get(allowHeader))
+	assert.Equal("", controller.MethodCalled)
+
+	recorder = serve(server, http.MethodPut, "/")
+	assert.Equal(http.StatusAccepted, recorder.Code)
+	assert.Equal(http.MethodPut, controller.MethodCalled)
+}
+
+func TestServeHTTPMethodNotAllowedByController(t *testing.T) {
+	assert := assert.New(t)
+	controller := &rejectingPutController{TestController: NewTestController("Rejecting")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodPut, "/")
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal("GET, HEAD, POST, PATCH, DELETE, OPTIONS", recorder.Header().Get(allowHeader))
+	// the rejected method is no longer reported as allowed, but is still
+	// dispatched to the controller
+	assert.Equal([]string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodDelete,
+		http.MethodOptions}, AllowedMethods(controller))
+	controller.MethodCalled = ""
+	recorder = serve(server, http.MethodPut, "/")
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(http.MethodPut, controller.MethodCalled)
+
+	declared := &rejectingGetController{}
+	server = CreateRESTServer(":8080", declared)
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		recorder = serve(server, method, "/")
+		assert.Equal(http.StatusMethodNotAllowed, recorder.Code, method)
+		assert.Equal("PUT, OPTIONS", recorder.Header().Get(allowHeader), method)
+	}
+}
+
+func TestServeHTTPUnknownMethod(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+
+	recorder := serve(server, "FOO", "/")
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal("GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", recorder.Header().Get(allowHeader))
+
+	server = CreateRESTServer(":8080", &declaredMethodsController{TestController: NewTestController("Declared")})
+	recorder = serve(server, "FOO", "/")
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal("GET, HEAD, PUT, OPTIONS", recorder.Header().Get(allowHeader))
+}
+
+func TestServeHTTPOptionsFromController(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+
+	recorder := serve(server, http.MethodOptions, "/")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal(http.MethodOptions, controller.MethodCalled)
+}
+
+func TestServeHTTPOptionsAutomatic(t *testing.T) {
+	assert := assert.New(t)
+	controller := &rejectingOptionsController{TestController: NewTestController("Rejecting")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodOptions, "/")
+	assert.Equal(http.StatusNoContent, recorder.Code)
+	assert.Equal("GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", recorder.Header().Get(allowHeader))
+	assert.Empty(recorder.Body.String())
+}
+
+func TestServeHTTPOptionsAutomaticDeclared(t *testing.T) {
+	assert := assert.New(t)
+	controller := &declaredMethodsController{TestController: NewTestController("Declared")}
+	server := CreateRESTServer(":8080", controller)
+
+	recorder := serve(server, http.MethodOptions, "/")
+	assert.Equal(http.StatusNoContent, recorder.Code)
+	assert.Equal("GET, HEAD, PUT, OPTIONS", recorder.Header().Get(allowHeader))
+	assert.Equal("", controller.MethodCalled)
+}
+
+func TestServeHTTPHead(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+
+	get := serve(server, http.MethodGet, "/")
+	head := serve(server, http.MethodHead, "/")
+	assert.Equal(http.MethodGet, controller.MethodCalled)
+	assert.Equal(http.StatusOK, head.Code)
+	assert.Empty(head.Body.String())
+	assert.Equal(get.Header().Get(contentTypeHeader), head.Header().Get(contentTypeHeader))
+	assert.Equal(strconv.Itoa(get.Body.Len()), head.Header().Get(contentLengthHeader))
+}
+
+func TestServeHTTPHeadStreamed(t *testing.T) {
+	assert := assert.New(t)
+	controller := &streamingController{TestController: NewTestController("Streaming")}
+	server := CreateRESTServer(":8080", controller)
+
+	head := serve(server, http.MethodHead, "/")
+	assert.Equal(http.StatusOK, head.Code)
+	assert.Empty(head.Body.String())
+	assert.Equal("text/plain", head.Header().Get(contentTypeHeader))
+	assert.Equal(strconv.Itoa(len("streamed body")), head.Header().Get(contentLengthHeader))
+}
+
+func TestServeHTTPRequestTimeout(t *testing.T) {
+	assert := assert.New(t)
+	controller := &waitingController{TestController: NewTestController("Waiting")}
+	server := CreateRESTServer(":8080", controller)
+	server.RequestTimeout = 10 * time.Millisecond
+
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(recorder.Body.String(), qerror.RequestTimeout)
+	assert.Equal(stdcontext.DeadlineExceeded, controller.err)
+}
+
+func TestServeHTTPRouteTimeout(t *testing.T) {
+	assert := assert.New(t)
+	controller := &waitingController{TestController: NewTestController("Waiting")}
+	server := CreateRESTServer(":8080", nil)
+	server.Router.AddRoute("slow", controller)
+	server.Router.AddRoute("fast", controller)
+	server.RequestTimeout = time.Hour
+	server.SetRouteTimeout("fast", 10*time.Millisecond)
+
+	recorder := serve(server, http.MethodGet, "/fast")
+	assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+
+	server.SetRouteTimeout("slow", 0)
+	recorder = serve(server, http.MethodGet, "/slow")
+	assert.Equal(http.StatusOK, recorder.Code)
+}
+
+func TestServerTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	server := CreateRESTServer(":8080", nil)
+	assert.Equal(DefaultReadTimeout, server.readTimeout())
+	assert.Equal(DefaultWriteTimeout, server.writeTimeout())
+
+	// the write timeout allows request-timeout errors to be returned
+	server.RequestTimeout = 30 * time.Second
+	server.SetRouteTimeout("slow", time.Minute)
+	assert.Equal(time.Minute+DefaultWriteTimeout, server.writeTimeout())
+
+	server.ReadTimeout = -1
+	server.WriteTimeout = 2 * time.Minute
+	assert.Equal(time.Duration(-1), server.readTimeout())
+	assert.Equal(2*time.Minute, server.writeTimeout())
+}
+
+func TestServeHTTPClientDisconnect(t *testing.T) {
+	assert := assert.New(t)
+	controller := &waitingController{TestController: NewTestController("Waiting")}
+	server := CreateRESTServer(":8080", controller)
+	disconnected := false
+	server.Use(func(context *Context, next Handler) {
+		next(context)
+		disconnected = context.Disconnected()
+	})
+
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	request := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	server.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(stdcontext.Canceled, controller.err)
+	assert.True(disconnected)
+}
+
+func TestServeHTTPRequestID(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+	var contextID string
+	server.Use(func(context *Context, next Handler) {
+		contextID = context.RequestID
+		next(context)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(DefaultRequestIDHeader, "upstream-id")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal("upstream-id", recorder.Header().Get(DefaultRequestIDHeader))
+	assert.Equal("upstream-id", contextID)
+
+	recorder = serve(server, http.MethodGet, "/")
+	assert.NotEmpty(contextID)
+	assert.NotEqual("upstream-id", contextID)
+	assert.Equal(contextID, recorder.Header().Get(DefaultRequestIDHeader))
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(DefaultRequestIDHeader, "contains spaces")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.NotEqual("contains spaces", recorder.Header().Get(DefaultRequestIDHeader))
+	assert.NotEmpty(recorder.Header().Get(DefaultRequestIDHeader))
+}
+
+func TestServeHTTPRequestIDCustomHeader(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+	server.RequestIDHeader = "X-Correlation-ID"
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Correlation-ID", "correlated")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal("correlated", recorder.Header().Get("X-Correlation-ID"))
+	assert.Empty(recorder.Header().Get(DefaultRequestIDHeader))
+}
+
+func TestServeHTTPRequestIDInError(t *testing.T) {
+	assert := assert.New(t)
+	controller := NewTestController("HTTP Test")
+	server := CreateRESTServer(":8080", &controller)
+
+	request := httptest.NewRequest(http.MethodGet, "/does/not/exist", nil)
+	request.Header.Set(DefaultRequestIDHeader, "upstream-id")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	restError := &qerror.RestError{}
+	assert.NoError(json.Unmarshal(recorder.Body.Bytes(), restError))
+	assert.Equal(qerror.NotFound, restError.Code)
+	assert.Equal("upstream-id", restError.RequestID)
+}
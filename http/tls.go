@@ -0,0 +1,119 @@
+package http
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Kasita-Inc/gadget/log"
+)
+
+// DefaultCertificateReloadInterval is how often the certificate files are
+// checked for changes when no interval is configured on the RESTServer.
+const DefaultCertificateReloadInterval = time.Minute
+
+// CertificateLoader loads a certificate / key pair from disk and serves it
+// to the TLS handshake. The pair is reloaded when the files change or the
+// process receives a SIGHUP, existing connections are unaffected.
+type CertificateLoader struct {
+	CertificateFile string
+	KeyFile         string
+
+	mutex               sync.RWMutex
+	certificate         *tls.Certificate
+	certificateModified time.Time
+	keyModified         time.Time
+}
+
+// NewCertificateLoader initializes a CertificateLoader and performs the
+// initial load of the certificate / key pair.
+func NewCertificateLoader(certificateFile, keyFile string) (*CertificateLoader, error) {
+	loader := &CertificateLoader{CertificateFile: certificateFile, KeyFile: keyFile}
+	return loader, loader.Reload()
+}
+
+// Reload reads the certificate / key pair from disk and replaces the
+// certificate being served. On error the previous certificate is kept.
+func (loader *CertificateLoader) Reload() error {
+	certificateModified, keyModified, err := loader.modified()
+	if err != nil {
+		return err
+	}
+	certificate, err := tls.LoadX509KeyPair(loader.CertificateFile, loader.KeyFile)
+	if err != nil {
+		return err
+	}
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	loader.certificate = &certificate
+	loader.certificateModified = certificateModified
+	loader.keyModified = keyModified
+	return nil
+}
+
+func (loader *CertificateLoader) modified() (time.Time, time.Time, error) {
+	certificateInfo, err := os.Stat(loader.CertificateFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(loader.KeyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certificateInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// reloadIfModified reloads the certificate / key pair if either file has a
+// different modification time than when it was last loaded.
+func (loader *CertificateLoader) reloadIfModified() error {
+	certificateModified, keyModified, err := loader.modified()
+	if err != nil {
+		return err
+	}
+	loader.mutex.RLock()
+	unchanged := certificateModified.Equal(loader.certificateModified) && keyModified.Equal(loader.keyModified)
+	loader.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return loader.Reload()
+}
+
+// GetCertificate returns the currently loaded certificate, it is intended
+// to be used as tls.Config.GetCertificate.
+func (loader *CertificateLoader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	loader.mutex.RLock()
+	defer loader.mutex.RUnlock()
+	return loader.certificate, nil
+}
+
+// Watch polls the certificate files for changes on the passed interval and
+// listens for SIGHUP, reloading the pair when either occurs. Watch blocks
+// until the stop channel is closed.
+func (loader *CertificateLoader) Watch(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultCertificateReloadInterval
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-hangup:
+			if err := loader.Reload(); err != nil {
+				log.Errorf("failed to reload certificate '%s' on SIGHUP: %s", loader.CertificateFile, err)
+			}
+		case <-ticker.C:
+			if err := loader.reloadIfModified(); err != nil {
+				log.Errorf("failed to reload certificate '%s': %s", loader.CertificateFile, err)
+			}
+		}
+	}
+}
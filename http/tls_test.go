@@ -0,0 +1,172 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+// writeSelfSignedCertificate generates a self signed certificate / key pair
+// with the passed serial number and writes them to the passed paths.
+func writeSelfSignedCertificate(t *testing.T, serial int64, certificateFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(certificateFile, certificatePEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createCertificateFiles(t *testing.T, serial int64) (string, string, string) {
+	dir, err := ioutil.TempDir("", "quimby-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeSelfSignedCertificate(t, serial, certificateFile, keyFile)
+	return dir, certificateFile, keyFile
+}
+
+func loadedSerial(loader *CertificateLoader) int64 {
+	certificate, _ := loader.GetCertificate(nil)
+	parsed, _ := x509.ParseCertificate(certificate.Certificate[0])
+	return parsed.SerialNumber.Int64()
+}
+
+// touch moves the modification time of the files forward so that changes
+// are detected regardless of file system timestamp granularity.
+func touch(t *testing.T, offset time.Duration, files ...string) {
+	for _, file := range files {
+		modified := time.Now().Add(offset)
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestCertificateLoaderReloadIfModified(t *testing.T) {
+	assert := assert.New(t)
+	dir, certificateFile, keyFile := createCertificateFiles(t, 1)
+	defer os.RemoveAll(dir)
+
+	loader, err := NewCertificateLoader(certificateFile, keyFile)
+	assert.NoError(err)
+	assert.Equal(int64(1), loadedSerial(loader))
+
+	// unchanged files should not reload
+	assert.NoError(loader.reloadIfModified())
+	assert.Equal(int64(1), loadedSerial(loader))
+
+	writeSelfSignedCertificate(t, 2, certificateFile, keyFile)
+	touch(t, time.Minute, certificateFile, keyFile)
+	assert.NoError(loader.reloadIfModified())
+	assert.Equal(int64(2), loadedSerial(loader))
+}
+
+func TestCertificateLoaderKeepsCertificateOnError(t *testing.T) {
+	assert := assert.New(t)
+	dir, certificateFile, keyFile := createCertificateFiles(t, 1)
+	defer os.RemoveAll(dir)
+
+	loader, err := NewCertificateLoader(certificateFile, keyFile)
+	assert.NoError(err)
+
+	assert.NoError(ioutil.WriteFile(certificateFile, []byte("garbage"), 0600))
+	touch(t, time.Minute, certificateFile)
+	assert.Error(loader.reloadIfModified())
+	assert.Equal(int64(1), loadedSerial(loader))
+}
+
+func TestNewCertificateLoaderMissingFiles(t *testing.T) {
+	_, err := NewCertificateLoader("does-not-exist.pem", "does-not-exist.key")
+	assert.Error(t, err)
+}
+
+func TestTLSConfigServesReloadedCertificate(t *testing.T) {
+	assert := assert.New(t)
+	dir, certificateFile, keyFile := createCertificateFiles(t, 1)
+	defer os.RemoveAll(dir)
+
+	controller := NewTestController("TLS Test")
+	server := CreateRESTServer(":0", &controller)
+	server.ConfigureTLS = func(config *tls.Config) {
+		config.MinVersion = tls.VersionTLS13
+	}
+	loader, err := NewCertificateLoader(certificateFile, keyFile)
+	assert.NoError(err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(err) {
+		return
+	}
+	defer listener.Close()
+	go http.Serve(tls.NewListener(listener, server.createTLSConfig(loader)), server)
+
+	get := func() *http.Response {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		response, err := client.Get("https://" + listener.Addr().String() + "/")
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		response.Body.Close()
+		return response
+	}
+
+	response := get()
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.Equal(uint16(tls.VersionTLS13), response.TLS.Version)
+	assert.Equal(int64(1), response.TLS.PeerCertificates[0].SerialNumber.Int64())
+
+	writeSelfSignedCertificate(t, 2, certificateFile, keyFile)
+	assert.NoError(loader.Reload())
+	response = get()
+	assert.Equal(int64(2), response.TLS.PeerCertificates[0].SerialNumber.Int64())
+}
@@ -0,0 +1,295 @@
+package http
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	traceparentHeader  = "traceparent"
+	tracestateHeader   = "tracestate"
+	traceparentVersion = "00"
+	sampledFlag        = 0x01
+)
+
+// Span attribute keys recorded for requests.
+const (
+	AttributeHTTPMethod     = "http.method"
+	AttributeHTTPRoute      = "http.route"
+	AttributeHTTPStatusCode = "http.status_code"
+	AttributeErrorCode      = "error.code"
+	AttributeRequestID      = "request.id"
+)
+
+type spanKey struct{}
+
+// TraceID identifies a trace across services.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that the TraceID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return TraceID{} != id
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that the SpanID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return SpanID{} != id
+}
+
+// SpanContext is the portion of a span propagated between services in the
+// W3C traceparent and tracestate headers.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote is true if the SpanContext was extracted from a request.
+	Remote bool
+}
+
+// IsValid checks that the SpanContext has a trace and span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Sampled checks if the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sampledFlag == sc.Flags&sampledFlag
+}
+
+// Traceparent formats the SpanContext as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// Inject sets the traceparent and tracestate headers on the passed header so
+// the trace continues in the service receiving the request.
+func (sc SpanContext) Inject(header http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(traceparentHeader, sc.Traceparent())
+	if "" != sc.TraceState {
+		header.Set(tracestateHeader, sc.TraceState)
+	}
+}
+
+// ParseTraceparent parses a W3C traceparent header value such as
+// '00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'.
+func ParseTraceparent(value string) (SpanContext, error) {
+	sc := SpanContext{Remote: true}
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent '%s'", value)
+	}
+	if "ff" == parts[0] || (traceparentVersion == parts[0] && 4 != len(parts)) {
+		return sc, fmt.Errorf("invalid traceparent version '%s'", value)
+	}
+	for _, part := range parts[:4] {
+		if strings.ToLower(part) != part {
+			return sc, fmt.Errorf("invalid traceparent '%s'", value)
+		}
+	}
+	var flags [1]byte
+	_, versionErr := hex.Decode(flags[:], []byte(parts[0]))
+	_, traceErr := hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	_, spanErr := hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	_, flagsErr := hex.Decode(flags[:], []byte(parts[3]))
+	if nil != versionErr || nil != traceErr || nil != spanErr || nil != flagsErr || !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent '%s'", value)
+	}
+	sc.Flags = flags[0]
+	return sc, nil
+}
+
+// extractSpanContext returns the SpanContext propagated in the request
+// headers, or an invalid SpanContext if there is none.
+func extractSpanContext(request *http.Request) SpanContext {
+	sc, err := ParseTraceparent(request.Header.Get(traceparentHeader))
+	if err != nil {
+		return SpanContext{}
+	}
+	sc.TraceState = strings.TrimSpace(request.Header.Get(tracestateHeader))
+	return sc
+}
+
+// Span records a timed operation within a trace. The methods of a nil Span
+// do nothing so code may create child spans whether or not tracing is
+// enabled.
+type Span struct {
+	Name        string
+	SpanContext SpanContext
+	Parent      SpanContext
+	Start       time.Time
+	End         time.Time
+
+	tracer     *Tracer
+	mutex      sync.Mutex
+	attributes map[string]interface{}
+	ended      bool
+}
+
+// SetAttribute records the key value pair on the span.
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if nil == span {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.attributes[key] = value
+}
+
+// Attributes returns a copy of the attributes recorded on the span.
+func (span *Span) Attributes() map[string]interface{} {
+	attributes := map[string]interface{}{}
+	if nil == span {
+		return attributes
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	for k, v := range span.attributes {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+// StartChild starts a span that is a child of this span.
+func (span *Span) StartChild(name string) *Span {
+	if nil == span {
+		return nil
+	}
+	return span.tracer.StartSpan(name, span.SpanContext)
+}
+
+// Finish ends the span and passes it to the exporter if it is sampled. Only
+// the first call has an effect.
+func (span *Span) Finish() {
+	if nil == span {
+		return
+	}
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.End = time.Now()
+	span.mutex.Unlock()
+	if span.SpanContext.Sampled() && nil != span.tracer.Exporter {
+		span.tracer.Exporter.Export(span)
+	}
+}
+
+// SpanFromContext returns the span stored on the passed context.Context, or
+// nil if there is none.
+func SpanFromContext(ctx stdcontext.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanExporter receives spans when they finish.
+type SpanExporter interface {
+	Export(span *Span)
+}
+
+// InMemoryExporter holds finished spans in memory, it is intended for tests.
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+// Export holds the passed span.
+func (exporter *InMemoryExporter) Export(span *Span) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.spans = append(exporter.spans, span)
+}
+
+// Spans returns the spans exported in the order they finished.
+func (exporter *InMemoryExporter) Spans() []*Span {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	return append([]*Span{}, exporter.spans...)
+}
+
+// Reset discards the spans exported.
+func (exporter *InMemoryExporter) Reset() {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.spans = nil
+}
+
+// Tracer starts spans and passes them to the Exporter when they finish.
+type Tracer struct {
+	Exporter SpanExporter
+}
+
+// NewTracer creates a Tracer exporting spans to the passed exporter.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+// StartSpan starts a span continuing the trace of the passed parent, or a
+// new sampled trace if the parent is not valid.
+func (tracer *Tracer) StartSpan(name string, parent SpanContext) *Span {
+	span := &Span{
+		Name:       name,
+		Parent:     parent,
+		Start:      time.Now(),
+		tracer:     tracer,
+		attributes: map[string]interface{}{},
+	}
+	if parent.IsValid() {
+		span.SpanContext.TraceID = parent.TraceID
+		span.SpanContext.Flags = parent.Flags
+		span.SpanContext.TraceState = parent.TraceState
+	} else {
+		rand.Read(span.SpanContext.TraceID[:])
+		span.SpanContext.Flags = sampledFlag
+	}
+	rand.Read(span.SpanContext.SpanID[:])
+	return span
+}
+
+// startRequestSpan starts the span for the request tracked by the passed
+// Context, named after the route template.
+func (server *RESTServer) startRequestSpan(context *Context) {
+	name := unmatchedRoute
+	if nil != context.Route {
+		name = context.Route.TemplateRoute
+	}
+	context.span = server.Tracer.StartSpan(name, extractSpanContext(context.Request))
+	context.span.SetAttribute(AttributeHTTPMethod, context.Method)
+	context.span.SetAttribute(AttributeRequestID, context.RequestID)
+	if nil != context.Route {
+		context.span.SetAttribute(AttributeHTTPRoute, context.Route.TemplateRoute)
+	}
+	context.WithValue(spanKey{}, context.span)
+}
+
+// finishRequestSpan records the outcome of the request and finishes its span.
+func finishRequestSpan(context *Context) {
+	context.span.SetAttribute(AttributeHTTPStatusCode, context.Status())
+	if context.HasError() {
+		context.span.SetAttribute(AttributeErrorCode, context.Error.Code)
+	}
+	context.span.Finish()
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// tracingController starts a child span from the request span.
+type tracingController struct {
+	TestController
+	span *Span
+}
+
+func (controller *tracingController) Get(context *Context) {
+	child := context.Span().StartChild("load")
+	child.SetAttribute("widgets", 2)
+	child.Finish()
+	controller.span = SpanFromContext(context)
+	context.SetResponse("traced", http.StatusOK)
+}
+
+func createTracingServer() (*RESTServer, *tracingController, *InMemoryExporter) {
+	exporter := &InMemoryExporter{}
+	controller := &tracingController{TestController: NewTestController("Tracing")}
+	server := CreateRESTServer(":8080", nil)
+	server.Router.AddRoute("widgets/{{id}}", controller)
+	server.Tracer = NewTracer(exporter)
+	return server, controller, exporter
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestParseTraceparent(t *testing.T) {
+	assert := assert.New(t)
+	sc, err := ParseTraceparent(testTraceparent)
+	assert.NoError(err)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal("00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(sc.Sampled())
+	assert.True(sc.Remote)
+	assert.Equal(testTraceparent, sc.Traceparent())
+
+	// future versions may append fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.NoError(err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(invalid)
+		assert.Error(err, invalid)
+	}
+}
+
+func TestServeHTTPSpan(t *testing.T) {
+	assert := assert.New(t)
+	server, controller, exporter := createTracingServer()
+
+	request := httptest.NewRequest(http.MethodGet, "/widgets/1", nil)
+	request.Header.Set(traceparentHeader, testTraceparent)
+	request.Header.Set(tracestateHeader, "vendor=value")
+	request.Header.Set(DefaultRequestIDHeader, "traced")
+	server.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := exporter.Spans()
+	if !assert.Len(spans, 2) {
+		return
+	}
+	child, span := spans[0], spans[1]
+	assert.Equal(span, controller.span)
+	assert.Equal("widgets/{{id}}", span.Name)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID.String())
+	assert.Equal("00f067aa0ba902b7", span.Parent.SpanID.String())
+	assert.Equal("vendor=value", span.SpanContext.TraceState)
+	assert.NotEqual(span.Parent.SpanID, span.SpanContext.SpanID)
+	assert.False(span.End.Before(span.Start))
+	assert.Equal(map[string]interface{}{
+		AttributeHTTPMethod:     http.MethodGet,
+		AttributeHTTPRoute:      "widgets/{{id}}",
+		AttributeHTTPStatusCode: http.StatusOK,
+		AttributeRequestID:      "traced",
+	}, span.Attributes())
+
+	assert.Equal("load", child.Name)
+	assert.Equal(span.SpanContext.TraceID, child.SpanContext.TraceID)
+	assert.Equal(span.SpanContext.SpanID, child.Parent.SpanID)
+	assert.Equal(2, child.Attributes()["widgets"])
+}
+
+func TestServeHTTPSpanNewTrace(t *testing.T) {
+	assert := assert.New(t)
+	server, _, exporter := createTracingServer()
+
+	serve(server, http.MethodGet, "/does/not/exist")
+	spans := exporter.Spans()
+	if assert.Len(spans, 1) {
+		assert.Equal(unmatchedRoute, spans[0].Name)
+		assert.True(spans[0].SpanContext.IsValid())
+		assert.False(spans[0].Parent.IsValid())
+		assert.Equal(http.StatusNotFound, spans[0].Attributes()[AttributeHTTPStatusCode])
+		assert.Equal(qerror.NotFound, spans[0].Attributes()[AttributeErrorCode])
+	}
+}
+
+func TestServeHTTPSpanNotSampled(t *testing.T) {
+	assert := assert.New(t)
+	server, _, exporter := createTracingServer()
+
+	request := httptest.NewRequest(http.MethodGet, "/widgets/1", nil)
+	request.Header.Set(traceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	server.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Empty(exporter.Spans())
+}
+
+func TestNilSpan(t *testing.T) {
+	assert := assert.New(t)
+	controller := &tracingController{TestController: NewTestController("Tracing")}
+	server := CreateRESTServer(":8080", controller)
+	recorder := serve(server, http.MethodGet, "/")
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Nil(controller.span)
+}
+
+func TestSpanContextInject(t *testing.T) {
+	assert := assert.New(t)
+	sc, _ := ParseTraceparent(testTraceparent)
+	sc.TraceState = "vendor=value"
+	header := http.Header{}
+	sc.Inject(header)
+	assert.Equal(testTraceparent, header.Get(traceparentHeader))
+	assert.Equal("vendor=value", header.Get(tracestateHeader))
+
+	header = http.Header{}
+	SpanContext{}.Inject(header)
+	assert.Empty(header)
+}
@@ -0,0 +1,401 @@
+package http
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Kasita-Inc/gadget/log"
+	"github.com/Kasita-Inc/gadget/stringutil"
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+const (
+	validateTag    = "validate"
+	jsonTag        = "json"
+	ruleSeparator  = ","
+	ruleParameter  = "="
+	diveRule       = "dive"
+	eqFieldRule    = "eqfield"
+	regexRule      = "regex"
+	omitEmptyRule  = "omitempty"
+	fieldToken     = "{field}"
+	parameterToken = "{parameter}"
+)
+
+// ValidationRule checks a field value against the parameter of the rule,
+// such as '3' for 'min=3'. Pointers are dereferenced before rules are
+// checked.
+type ValidationRule func(value reflect.Value, parameter string) bool
+
+type validationRule struct {
+	code    string
+	message string
+	// parameterized rules format their message with the rule parameter after
+	// the field path
+	parameterized bool
+	check         ValidationRule
+}
+
+var (
+	emailExpression = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s.]+$`)
+	regexCache      = map[string]*regexp.Regexp{}
+	validationMutex sync.RWMutex
+)
+
+// validationRules report violations with the message template registered for
+// their code, see qerror.Register.
+var validationRules = map[string]validationRule{
+	"min":   {code: qerror.TooSmall, parameterized: true, check: isMin},
+	"max":   {code: qerror.TooLarge, parameterized: true, check: isMax},
+	"len":   {code: qerror.InvalidLength, parameterized: true, check: isLen},
+	"regex": {code: qerror.InvalidFormat, check: isMatch},
+	"oneof": {code: qerror.InvalidOption, parameterized: true, check: isOneOf},
+	"email": {code: qerror.InvalidFormat, check: isEmail},
+	"uuid":  {code: qerror.InvalidFormat, check: isUUIDValue},
+}
+
+var (
+	requiredRule = validationRule{code: qerror.CannotBeBlank}
+	matchRule    = validationRule{code: qerror.FieldMismatch, parameterized: true}
+)
+
+// RegisterValidationRule makes a named rule available to 'validate' tags,
+// e.g. registering 'hex' allows fields such as `validate:"required,hex"`.
+// Violations are reported as a FieldError with the passed code. If the code
+// is registered, see qerror.Register, its localized message template is
+// formatted with the field path, followed by the rule parameter if the passed
+// message has '{parameter}'. Otherwise the passed message is used, where
+// '{field}' and '{parameter}' are replaced by the field path and parameter.
+func RegisterValidationRule(name, code, message string, rule ValidationRule) {
+	validationMutex.Lock()
+	defer validationMutex.Unlock()
+	validationRules[name] = validationRule{
+		code:          code,
+		message:       message,
+		parameterized: strings.Contains(message, parameterToken),
+		check:         rule,
+	}
+}
+
+// Validate checks the target against the rules in the 'validate' tags of its
+// fields, returning FieldErrors with a FieldError for each violation or nil.
+// Rules are separated by commas:
+//
+//	type WidgetRequest struct {
+//		SerialNumber string   `json:"serial_number" validate:"required,len=12"`
+//		Color        string   `json:"color" validate:"omitempty,oneof=red green blue"`
+//		Tags         []string `json:"tags" validate:"max=5,dive,min=2"`
+//		Password     string   `json:"password" validate:"required,min=8"`
+//		Confirmation string   `json:"confirmation" validate:"eqfield=Password"`
+//	}
+//
+// The built in rules are required, omitempty, min, max, len, regex, oneof,
+// email, uuid and eqfield, which compares the field to another field of the
+// struct. min, max and len compare the length of strings, slices and maps and
+// the value of numbers. Rules after dive are checked against each element of a
+// slice or map. Empty fields are checked against every rule unless they have
+// the omitempty rule, nil pointers are only checked by required. Nested structs
+// are validated, and fields are reported by their JSON path such as
+// 'items[0].name'. The regex rule must match the whole value, and its
+// parameter extends to the end of the tag, so it must be the last rule.
+func Validate(target interface{}) error {
+	return validateLocalized(target)
+}
+
+// validateLocalized validates the target, with messages localized for the
+// passed languages.
+func validateLocalized(target interface{}, languages ...string) error {
+	validator := &validator{languages: languages}
+	validator.validateValue(reflect.ValueOf(target), "")
+	if len(validator.errors) > 0 {
+		return validator.errors
+	}
+	return nil
+}
+
+// validate validates the target, adding a FieldError for each violation to
+// the Context.
+func (context *Context) validate(target interface{}) error {
+	if err := validateLocalized(target, context.languages()...); nil != err {
+		context.setBindError(err)
+		return context.Error
+	}
+	return nil
+}
+
+type validator struct {
+	errors    FieldErrors
+	languages []string
+}
+
+// validateValue validates the structs in the value, which may be a pointer,
+// struct, slice or map.
+func (validator *validator) validateValue(v reflect.Value, path string) {
+	for reflect.Ptr == v.Kind() || reflect.Interface == v.Kind() {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if timeType == v.Type() || formFileType.Elem() == v.Type() {
+			return
+		}
+		validator.validateStruct(v, path)
+	case reflect.Slice, reflect.Array:
+		if !containsStruct(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			validator.validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if !containsStruct(v.Type().Elem()) {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			validator.validateValue(v.MapIndex(key), joinKey(path, fmt.Sprint(key.Interface())))
+		}
+	}
+}
+
+// containsStruct checks if values of the type may contain a struct to
+// validate, so slices and maps of scalars are not walked.
+func containsStruct(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return timeType != t && formFileType.Elem() != t
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsStruct(t.Elem())
+	}
+	return false
+}
+
+func (validator *validator) validateStruct(v reflect.Value, path string) {
+	eachBoundField(v, func(field reflect.StructField, value reflect.Value) {
+		fieldPath := joinKey(path, jsonFieldName(field))
+		rules := parseRules(field.Tag.Get(validateTag))
+		if validator.validateField(v, value, fieldPath, rules) {
+			validator.validateValue(value, fieldPath)
+		}
+	})
+}
+
+// validateField checks the rules against the field of the passed struct,
+// returning false if a rule is violated or the elements of the field were
+// validated by dive.
+func (validator *validator) validateField(parent, value reflect.Value, path string, rules []string) bool {
+	if isBlank(value) {
+		// required and omitempty rules after dive apply to the elements
+		fieldRules := rules
+		for i, rule := range rules {
+			if diveRule == strings.TrimSpace(rule) {
+				fieldRules = rules[:i]
+				break
+			}
+		}
+		if hasOption(fieldRules, requiredOption) {
+			validator.fail(requiredRule, path, "")
+			return false
+		}
+		if isNil(value) || hasOption(fieldRules, omitEmptyRule) {
+			return true
+		}
+	}
+	value = reflect.Indirect(value)
+	for i, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		name, parameter := rule, ""
+		if j := strings.Index(rule, ruleParameter); j >= 0 {
+			name, parameter = rule[:j], rule[j+1:]
+		}
+		switch name {
+		case "", requiredOption, omitEmptyRule:
+			continue
+		case diveRule:
+			validator.dive(parent, value, path, rules[i+1:])
+			// the elements have been validated by dive
+			return false
+		case eqFieldRule:
+			other := parent.FieldByName(parameter)
+			if !other.IsValid() || !reflect.DeepEqual(reflect.Indirect(other).Interface(), value.Interface()) {
+				validator.fail(matchRule, path, parameter)
+				return false
+			}
+			continue
+		}
+		validationMutex.RLock()
+		validationRule, ok := validationRules[name]
+		validationMutex.RUnlock()
+		if !ok {
+			log.Errorf("unknown validation rule '%s' for '%s'", name, path)
+			continue
+		}
+		if !validationRule.check(value, parameter) {
+			validator.fail(validationRule, path, parameter)
+			return false
+		}
+	}
+	return true
+}
+
+// dive checks the rules against each element of the slice or map.
+func (validator *validator) dive(parent, value reflect.Value, path string, rules []string) {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			if validator.validateField(parent, value.Index(i), elementPath, rules) {
+				validator.validateValue(value.Index(i), elementPath)
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			elementPath := joinKey(path, fmt.Sprint(key.Interface()))
+			if validator.validateField(parent, value.MapIndex(key), elementPath, rules) {
+				validator.validateValue(value.MapIndex(key), elementPath)
+			}
+		}
+	}
+}
+
+// fail reports a violation of the rule, with the message template registered
+// for its code or otherwise the message of the rule.
+func (validator *validator) fail(rule validationRule, path, parameter string) {
+	if _, registered := qerror.Lookup(rule.code); !registered {
+		replacer := strings.NewReplacer(fieldToken, path, parameterToken, parameter)
+		validator.errors = append(validator.errors, qerror.FieldError{
+			Code:    rule.code,
+			Message: replacer.Replace(rule.message),
+			Field:   path,
+		})
+		return
+	}
+	args := []interface{}{path}
+	if rule.parameterized {
+		args = append(args, parameter)
+	}
+	validator.errors = append(validator.errors, newFieldError(rule.code, path, validator.languages, args...))
+}
+
+// parseRules splits a 'validate' tag into its rules. The parameter of a regex
+// rule may contain commas so it extends to the end of the tag.
+func parseRules(tag string) []string {
+	rules := strings.Split(tag, ruleSeparator)
+	for i, rule := range rules {
+		if strings.HasPrefix(strings.TrimSpace(rule), regexRule+ruleParameter) {
+			return append(rules[:i], strings.Join(rules[i:], ruleSeparator))
+		}
+	}
+	return rules
+}
+
+// jsonFieldName returns the name of the field in JSON, or the name it is
+// bound from by Bind.
+func jsonFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get(jsonTag), ",")[0]; "" != name && "-" != name {
+		return name
+	}
+	if source, name, _ := boundSource(field); "" != source {
+		return name
+	}
+	return field.Name
+}
+
+// isNil checks if the value is a nil pointer or interface, which is missing
+// rather than empty.
+func isNil(v reflect.Value) bool {
+	return (reflect.Ptr == v.Kind() || reflect.Interface == v.Kind()) && v.IsNil()
+}
+
+// isBlank checks if the value is nil, zero, or a string of white space.
+func isBlank(v reflect.Value) bool {
+	if reflect.String == v.Kind() {
+		return stringutil.IsWhiteSpace(v.String())
+	}
+	if reflect.Slice == v.Kind() || reflect.Map == v.Kind() {
+		return 0 == v.Len()
+	}
+	return isZero(v)
+}
+
+// size returns the length of strings, slices and maps, or the value of
+// numbers.
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compareSize(v reflect.Value, parameter string, compare func(size, limit float64) bool) bool {
+	limit, err := strconv.ParseFloat(parameter, 64)
+	s, ok := size(v)
+	return nil == err && ok && compare(s, limit)
+}
+
+func isMin(v reflect.Value, parameter string) bool {
+	return compareSize(v, parameter, func(size, limit float64) bool { return size >= limit })
+}
+
+func isMax(v reflect.Value, parameter string) bool {
+	return compareSize(v, parameter, func(size, limit float64) bool { return size <= limit })
+}
+
+func isLen(v reflect.Value, parameter string) bool {
+	return compareSize(v, parameter, func(size, limit float64) bool { return size == limit })
+}
+
+// isMatch checks the whole value matches the regular expression, which is
+// anchored the same as route parameter constraints and compiled once. Values
+// never match an invalid expression.
+func isMatch(v reflect.Value, parameter string) bool {
+	validationMutex.RLock()
+	re, ok := regexCache[parameter]
+	validationMutex.RUnlock()
+	if !ok {
+		var err error
+		if re, err = regexp.Compile("^(?:" + parameter + ")$"); nil != err {
+			log.Errorf("invalid validation regex '%s': %s", parameter, err)
+		}
+		validationMutex.Lock()
+		regexCache[parameter] = re
+		validationMutex.Unlock()
+	}
+	return nil != re && reflect.String == v.Kind() && re.MatchString(v.String())
+}
+
+func isOneOf(v reflect.Value, parameter string) bool {
+	value := fmt.Sprint(v.Interface())
+	for _, option := range strings.Fields(parameter) {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmail(v reflect.Value, parameter string) bool {
+	return reflect.String == v.Kind() && emailExpression.MatchString(v.String())
+}
+
+func isUUIDValue(v reflect.Value, parameter string) bool {
+	return reflect.String == v.Kind() && isUUID(v.String())
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	qerror "github.com/Kasita-Inc/quimby/error"
+)
+
+/******************************************************
+ *          Supporting code for tests                 *
+ ******************************************************/
+
+type validatedPart struct {
+	Name     string `json:"name" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1,max=10"`
+}
+
+type validatedWidget struct {
+	SerialNumber string            `json:"serial_number" validate:"required,len=6"`
+	Color        string            `json:"color" validate:"omitempty,oneof=red green blue"`
+	Code         string            `json:"code" validate:"omitempty,regex=^[a-z]{1,3}$"`
+	Email        string            `json:"email" validate:"omitempty,email"`
+	Owner        string            `json:"owner" validate:"omitempty,uuid"`
+	Tags         []string          `json:"tags" validate:"max=2,dive,min=2"`
+	Labels       map[string]string `json:"labels" validate:"dive,required"`
+	Parts        []validatedPart   `json:"parts"`
+	Primary      *validatedPart    `json:"primary"`
+	Password     string            `json:"password" validate:"omitempty,min=8"`
+	Confirmation string            `json:"confirmation" validate:"eqfield=Password"`
+	Hex          string            `json:"hex" validate:"hex"`
+}
+
+func validWidget() *validatedWidget {
+	return &validatedWidget{
+		SerialNumber: "abc123",
+		Color:        "red",
+		Code:         "abc",
+		Email:        "roundy@example.com",
+		Owner:        "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Tags:         []string{"ab", "cd"},
+		Labels:       map[string]string{"env": "test"},
+		Parts:        []validatedPart{{Name: "bolt", Quantity: 2}},
+		Password:     "password",
+		Confirmation: "password",
+		Hex:          "ff00",
+	}
+}
+
+func init() {
+	RegisterValidationRule("hex", "invalid-hex", "'{field}' must be hexadecimal.",
+		func(value reflect.Value, parameter string) bool {
+			return "" == strings.Trim(value.String(), "0123456789abcdef")
+		})
+}
+
+/******************************************************
+ *                      Tests                         *
+ ******************************************************/
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(Validate(validWidget()))
+	assert.NoError(Validate(&validatedWidget{SerialNumber: "abc123"}))
+	assert.NoError(Validate([]*validatedWidget{validWidget()}))
+}
+
+func TestValidateViolations(t *testing.T) {
+	assert := assert.New(t)
+	widget := &validatedWidget{
+		SerialNumber: "  ",
+		Color:        "purple",
+		Code:         "ABC",
+		Email:        "roundy",
+		Owner:        "owner",
+		Tags:         []string{"ab", "c"},
+		Labels:       map[string]string{"env": ""},
+		Parts:        []validatedPart{{Name: "bolt", Quantity: 2}, {Quantity: 11}},
+		Primary:      &validatedPart{Name: "nut"},
+		Password:     "secret",
+		Confirmation: "other",
+		Hex:          "xyz",
+	}
+	err := Validate(widget)
+	assert.Equal(map[string]string{
+		"serial_number":     qerror.CannotBeBlank,
+		"color":             qerror.InvalidOption,
+		"code":              qerror.InvalidFormat,
+		"email":             qerror.InvalidFormat,
+		"owner":             qerror.InvalidFormat,
+		"tags[1]":           qerror.TooSmall,
+		"labels.env":        qerror.CannotBeBlank,
+		"parts[1].name":     qerror.CannotBeBlank,
+		"parts[1].quantity": qerror.TooLarge,
+		"primary.quantity":  qerror.TooSmall,
+		"password":          qerror.TooSmall,
+		"confirmation":      qerror.FieldMismatch,
+		"hex":               "invalid-hex",
+	}, fieldErrorCodes(err))
+	assert.Contains(err.Error(), "'parts[1].quantity' must be at most 10.")
+
+	// zero values are checked unless omitempty
+	err = Validate(&validatedPart{Name: "bolt"})
+	assert.Equal(map[string]string{"quantity": qerror.TooSmall}, fieldErrorCodes(err))
+
+	// empty values are only skipped by omitempty
+	err = Validate(&validatedWidget{SerialNumber: "abc1234", Tags: []string{"ab", "cd", "ef"}})
+	assert.Equal(map[string]string{
+		"serial_number": qerror.InvalidLength,
+		"tags":          qerror.TooLarge,
+	}, fieldErrorCodes(err))
+}
+
+func TestValidateScalarSlices(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(containsStruct(reflect.TypeOf([]*validatedPart{})))
+	assert.True(containsStruct(reflect.TypeOf(map[string][]interface{}{})))
+	assert.False(containsStruct(reflect.TypeOf([]byte{})))
+	assert.False(containsStruct(reflect.TypeOf(map[string][]string{})))
+
+	payload := &struct {
+		Data []byte `json:"data" validate:"required"`
+	}{Data: make([]byte, 5<<20)}
+	start := time.Now()
+	assert.NoError(Validate(payload))
+	assert.True(time.Since(start) < 100*time.Millisecond)
+}
+
+func TestValidateRegex(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"required", "regex=^[a-z]{1,3}$"}, parseRules("required,regex=^[a-z]{1,3}$"))
+	assert.Error(Validate(&validatedWidget{SerialNumber: "abc123", Code: "abcd"}))
+
+	// expressions match the whole value
+	unanchored := &struct {
+		Code string `json:"code" validate:"regex=[a-z]+"`
+	}{Code: "ABCd"}
+	assert.Equal(map[string]string{"code": qerror.InvalidFormat}, fieldErrorCodes(Validate(unanchored)))
+	unanchored.Code = "abcd"
+	assert.NoError(Validate(unanchored))
+
+	// invalid expressions do not panic and never match
+	invalid := &struct {
+		Code string `json:"code" validate:"regex=[a-"`
+	}{Code: "a"}
+	assert.NotPanics(func() {
+		assert.Equal(map[string]string{"code": qerror.InvalidFormat}, fieldErrorCodes(Validate(invalid)))
+	})
+}
+
+func TestReadObjectLocalizesFieldErrors(t *testing.T) {
+	assert := assert.New(t)
+	qerror.RegisterCatalog(qerror.MapCatalog{
+		"es": {qerror.TooLarge: "'%s' debe ser como máximo %s."},
+	})
+	context := createRequestContext(nil, newRequest(http.MethodPost, "/",
+		`{"serial_number":"abc123","parts":[{"name":"bolt","quantity":20}]}`, contentTypeHeader, MediaTypeJSON))
+	context.Request.Header.Set(acceptLanguageHeader, "es-ES")
+	assert.Error(context.ReadObject(&validatedWidget{}))
+	if assert.Len(context.Error.Details, 1) {
+		assert.Equal("'parts[0].quantity' debe ser como máximo 10.", context.Error.Details[0].(qerror.FieldError).Message)
+	}
+}
+
+func TestReadObjectValidates(t *testing.T) {
+	assert := assert.New(t)
+	context := createRequestContext(nil, newRequest(http.MethodPost, "/",
+		`{"serial_number":"abc123","parts":[{"name":"bolt","quantity":20}]}`, contentTypeHeader, MediaTypeJSON))
+	assert.Error(context.ReadObject(&validatedWidget{}))
+	assert.Equal(http.StatusBadRequest, context.Status())
+	assert.Equal(qerror.ValidationError, context.Error.Code)
+	assert.Equal(map[string]string{"parts[0].quantity": qerror.TooLarge}, fieldErrorCodes(context.Error))
+
+	context = createBindContext("/widgets/x", map[string]string{"id": "7"}, `{"name":"bolt"}`)
+	context.Request.Header.Set("X-Widget-Version", "v1")
+	assert.Error(context.Bind(&struct {
+		widgetBinding
+		Quantity int `query:"quantity" validate:"required"`
+	}{}))
+	assert.Equal(map[string]string{"quantity": qerror.CannotBeBlank}, fieldErrorCodes(context.Error))
+}